@@ -0,0 +1,18 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func createCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "create",
+		Short: "Create some ECS resource",
+		Run: func(c *cobra.Command, arg []string) {
+			c.Help()
+		},
+	}
+	command.AddCommand(
+		createScheduledTaskCmd(),
+	)
+
+	return command
+}
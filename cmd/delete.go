@@ -0,0 +1,18 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func deleteCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete some ECS resource",
+		Run: func(c *cobra.Command, arg []string) {
+			c.Help()
+		},
+	}
+	command.AddCommand(
+		deleteScheduledTaskCmd(),
+	)
+
+	return command
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func disableCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "disable",
+		Short: "Disable some ECS resource",
+		Run: func(c *cobra.Command, arg []string) {
+			c.Help()
+		},
+	}
+	command.AddCommand(
+		disableScheduledTaskCmd(),
+	)
+
+	return command
+}
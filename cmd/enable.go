@@ -0,0 +1,18 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func enableCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "enable",
+		Short: "Enable some ECS resource",
+		Run: func(c *cobra.Command, arg []string) {
+			c.Help()
+		},
+	}
+	command.AddCommand(
+		enableScheduledTaskCmd(),
+	)
+
+	return command
+}
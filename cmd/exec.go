@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	ecsdeploy "github.com/h3poteto/ecs-goploy/deploy"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type execCommand struct {
+	cluster        string
+	service        string
+	task           string
+	container      string
+	command        string
+	nonInteractive bool
+}
+
+func execCmd() *cobra.Command {
+	e := &execCommand{}
+	cmd := &cobra.Command{
+		Use:   "exec",
+		Short: "Execute a command in a running task of ECS",
+		Run:   e.exec,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&e.cluster, "cluster", "c", "", "Name of ECS cluster")
+	flags.StringVarP(&e.service, "service-name", "s", "", "Name of service. A running task of the service is selected")
+	flags.StringVar(&e.task, "task", "", "ARN or ID of the task. If this is set, service name is ignored")
+	flags.StringVarP(&e.container, "container-name", "n", "", "Name of the container. It can be omitted if the task has only one container")
+	flags.StringVar(&e.command, "command", "/bin/sh", "Command which is executed in the container")
+	flags.BoolVar(&e.nonInteractive, "non-interactive", false, "Do not connect stdin, and print the output of the command")
+
+	return cmd
+}
+
+func (e *execCommand) exec(cmd *cobra.Command, args []string) {
+	profile, region, verbose := generalConfig()
+	if !verbose {
+		log.SetLevel(log.ErrorLevel)
+	}
+	exec := ecsdeploy.NewExec(e.cluster, e.service, e.task, e.container, e.command, profile, region, verbose)
+	if e.nonInteractive {
+		output, err := exec.Output()
+		fmt.Print(output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := exec.Run(os.Stdin, os.Stdout, os.Stderr); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	ecsdeploy "github.com/h3poteto/ecs-goploy/deploy"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// hooks are shell commands which run around the deploy.
+type hooks struct {
+	pre       string
+	post      string
+	onFailure string
+}
+
+func (h *hooks) addFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringVar(&h.pre, "pre-hook", "", "Shell command which runs before the deploy. If it fails, the deploy is aborted")
+	flags.StringVar(&h.post, "post-hook", "", "Shell command which runs after the deploy succeeds")
+	flags.StringVar(&h.onFailure, "on-failure-hook", "", "Shell command which runs after the deploy fails or is rolled back")
+}
+
+// runPre runs the pre-hook. If it fails, the deploy should be aborted.
+func (h *hooks) runPre(env *ecsdeploy.HookEnvironment) error {
+	env.Status = ecsdeploy.StatusStarted
+	hook := &ecsdeploy.Hook{Command: h.pre}
+	if err := hook.Run(env); err != nil {
+		return errors.Wrap(err, "pre-hook aborts the deploy")
+	}
+	return nil
+}
+
+// runAfter runs the post-hook or the on-failure-hook according to the status.
+func (h *hooks) runAfter(env *ecsdeploy.HookEnvironment) error {
+	command := h.post
+	if env.Status != ecsdeploy.StatusSucceeded {
+		command = h.onFailure
+	}
+	hook := &ecsdeploy.Hook{Command: command}
+	return hook.Run(env)
+}
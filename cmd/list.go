@@ -0,0 +1,18 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func listCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "list",
+		Short: "List some ECS resource",
+		Run: func(c *cobra.Command, arg []string) {
+			c.Help()
+		},
+	}
+	command.AddCommand(
+		listScheduledTasksCmd(),
+	)
+
+	return command
+}
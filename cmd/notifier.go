@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	ecsdeploy "github.com/h3poteto/ecs-goploy/deploy"
+	"github.com/spf13/cobra"
+)
+
+// notifiers are webhooks which are notified when the deploy starts and finishes.
+type notifiers struct {
+	webhookURL      string
+	webhookTemplate string
+	slackWebhookURL string
+	slackChannel    string
+}
+
+func (n *notifiers) addFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringVar(&n.webhookURL, "webhook-url", "", "URL of the webhook which receives the result of the deploy as JSON")
+	flags.StringVar(&n.webhookTemplate, "webhook-template", "", "Go template of the JSON body of the webhook, ex: '{\"text\": {{json .Status}}}'. Default is none, and post the whole result")
+	flags.StringVar(&n.slackWebhookURL, "slack-webhook-url", "", "URL of the incoming webhook of Slack which receives the result of the deploy")
+	flags.StringVar(&n.slackChannel, "slack-channel", "", "Channel of Slack. Default is none, and use the channel of the incoming webhook")
+}
+
+// build returns notifiers which are specified with flags.
+func (n *notifiers) build() []ecsdeploy.Notifier {
+	result := []ecsdeploy.Notifier{}
+	if len(n.webhookURL) > 0 {
+		result = append(result, &ecsdeploy.WebhookNotifier{URL: n.webhookURL, Template: n.webhookTemplate})
+	}
+	if len(n.slackWebhookURL) > 0 {
+		result = append(result, &ecsdeploy.SlackNotifier{WebhookURL: n.slackWebhookURL, Channel: n.slackChannel})
+	}
+	return result
+}
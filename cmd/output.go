@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ecsdeploy "github.com/h3poteto/ecs-goploy/deploy"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// outputJSON returns whether the result of the command is printed as JSON.
+func outputJSON() bool {
+	switch output := viper.GetString("output"); output {
+	case "json":
+		return true
+	case "text", "":
+		return false
+	default:
+		log.Fatalf("output must be json or text: %s", output)
+	}
+	return false
+}
+
+// printJSON prints the result document as JSON.
+func printJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// errorMessage returns the message of the error for result documents.
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// deployOutput is a JSON document of the result of update service.
+type deployOutput struct {
+	Cluster                   string  `json:"cluster"`
+	Service                   string  `json:"service"`
+	TaskDefinitionArn         string  `json:"taskDefinitionArn,omitempty"`
+	PreviousTaskDefinitionArn string  `json:"previousTaskDefinitionArn,omitempty"`
+	DeploymentID              string  `json:"deploymentId,omitempty"`
+	DurationSeconds           float64 `json:"durationSeconds"`
+	RolledBack                bool    `json:"rolledBack"`
+	Error                     string  `json:"error,omitempty"`
+}
+
+func newDeployOutput(cluster, service string, result *ecsdeploy.DeployResult, err error) *deployOutput {
+	o := &deployOutput{
+		Cluster: cluster,
+		Service: service,
+		Error:   errorMessage(err),
+	}
+	if result != nil {
+		o.TaskDefinitionArn = result.TaskDefinitionArn
+		o.PreviousTaskDefinitionArn = result.PreviousTaskDefinitionArn
+		o.DeploymentID = result.DeploymentID
+		o.DurationSeconds = result.Duration.Seconds()
+		o.RolledBack = result.RolledBack
+	}
+	return o
+}
+
+// taskDefinitionOutput is a JSON document of the result of update task-definition.
+type taskDefinitionOutput struct {
+	TaskDefinitionArn         string `json:"taskDefinitionArn"`
+	PreviousTaskDefinitionArn string `json:"previousTaskDefinitionArn"`
+}
+
+// runTaskOutput is a JSON document of the result of run task.
+type runTaskOutput struct {
+	Cluster         string              `json:"cluster"`
+	DurationSeconds float64             `json:"durationSeconds"`
+	Tasks           []*taskResultOutput `json:"tasks"`
+	Error           string              `json:"error,omitempty"`
+}
+
+type taskResultOutput struct {
+	Shard             *int    `json:"shard,omitempty"`
+	TaskArn           string  `json:"taskArn,omitempty"`
+	TaskDefinitionArn string  `json:"taskDefinitionArn,omitempty"`
+	ExitCode          *int64  `json:"exitCode"`
+	DurationSeconds   float64 `json:"durationSeconds"`
+	StoppedReason     string  `json:"stoppedReason,omitempty"`
+	Succeeded         bool    `json:"succeeded"`
+	Error             string  `json:"error,omitempty"`
+}
+
+func newTaskResultOutputs(results []*ecsdeploy.TaskResult, batch bool) []*taskResultOutput {
+	outputs := []*taskResultOutput{}
+	for _, r := range results {
+		o := &taskResultOutput{
+			TaskArn:           r.TaskArn,
+			TaskDefinitionArn: r.TaskDefinitionArn,
+			ExitCode:          r.ExitCode,
+			DurationSeconds:   r.Duration.Seconds(),
+			StoppedReason:     r.StoppedReason,
+			Succeeded:         r.Succeeded(),
+			Error:             errorMessage(r.Err),
+		}
+		if batch {
+			shard := r.Shard
+			o.Shard = &shard
+		}
+		outputs = append(outputs, o)
+	}
+	return outputs
+}
+
+// scheduledTaskOutput is a JSON document of the result of updating scheduled tasks.
+type scheduledTaskOutput struct {
+	Rule                      string   `json:"rule"`
+	ScheduleExpression        string   `json:"scheduleExpression,omitempty"`
+	TargetIDs                 []string `json:"targetIds"`
+	TaskDefinitionArn         string   `json:"taskDefinitionArn,omitempty"`
+	PreviousTaskDefinitionArn string   `json:"previousTaskDefinitionArn,omitempty"`
+	Error                     string   `json:"error,omitempty"`
+}
+
+func newScheduledTaskOutput(result *ecsdeploy.ScheduledTaskResult) *scheduledTaskOutput {
+	o := &scheduledTaskOutput{
+		Rule:                      result.Rule,
+		ScheduleExpression:        result.ScheduleExpression,
+		TargetIDs:                 result.TargetIDs,
+		TaskDefinitionArn:         result.TaskDefinitionArn,
+		PreviousTaskDefinitionArn: result.PreviousTaskDefinitionArn,
+		Error:                     errorMessage(result.Err),
+	}
+	if o.TargetIDs == nil {
+		o.TargetIDs = []string{}
+	}
+	return o
+}
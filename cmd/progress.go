@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	ecsdeploy "github.com/h3poteto/ecs-goploy/deploy"
+)
+
+// progressObservers returns observers which print the progress of the command.
+// The progress is printed to stderr, and it is not printed when the result is printed as JSON.
+func progressObservers() []ecsdeploy.Observer {
+	if outputJSON() {
+		return nil
+	}
+	return []ecsdeploy.Observer{ecsdeploy.ObserverFunc(printProgress)}
+}
+
+// printProgress prints the event as a line of the progress.
+func printProgress(event *ecsdeploy.Event) {
+	var message string
+	switch event.Type {
+	case ecsdeploy.EventTaskDefinitionRegistered:
+		message = fmt.Sprintf("Registered task definition: %s", event.TaskDefinitionArn)
+	case ecsdeploy.EventServiceUpdated:
+		message = fmt.Sprintf("Updated service %s: deployment %s", event.Service, event.DeploymentID)
+	case ecsdeploy.EventNewTaskRunning:
+		message = fmt.Sprintf("New task is running: %s", event.TaskDefinitionArn)
+	case ecsdeploy.EventDeployFailed:
+		message = fmt.Sprintf("Deploy failed: %v", event.Err)
+	case ecsdeploy.EventRollbackStarted:
+		message = fmt.Sprintf("Rolling back to: %s", event.PreviousTaskDefinitionArn)
+	case ecsdeploy.EventRollbackCompleted:
+		message = fmt.Sprintf("Rolled back to: %s", event.PreviousTaskDefinitionArn)
+	case ecsdeploy.EventTasksStarted:
+		message = fmt.Sprintf("Started task: %s", strings.Join(event.TaskArns, ", "))
+	case ecsdeploy.EventTasksStopped:
+		message = fmt.Sprintf("Stopped task: %s", strings.Join(event.TaskArns, ", "))
+	case ecsdeploy.EventScheduledTaskUpdated:
+		message = fmt.Sprintf("Updated %s: %s", event.Rule, event.TaskDefinitionArn)
+	default:
+		return
+	}
+	fmt.Fprintln(os.Stderr, message)
+}
@@ -18,14 +18,24 @@ func init() {
 	RootCmd.PersistentFlags().StringP("profile", "", "", "AWS profile (detault is none, and use environment variables)")
 	RootCmd.PersistentFlags().StringP("region", "", "", "AWS region (default is none, and use AWS_DEFAULT_REGION)")
 	RootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose mode")
+	RootCmd.PersistentFlags().StringP("output", "o", "text", "Output format of the result, json or text")
 	viper.BindPFlag("profile", RootCmd.PersistentFlags().Lookup("profile"))
 	viper.BindPFlag("region", RootCmd.PersistentFlags().Lookup("region"))
 	viper.BindPFlag("verbose", RootCmd.PersistentFlags().Lookup("verbose"))
+	viper.BindPFlag("output", RootCmd.PersistentFlags().Lookup("output"))
 
 	RootCmd.AddCommand(
 		versionCmd(),
+		createCmd(),
+		deleteCmd(),
+		disableCmd(),
+		enableCmd(),
+		execCmd(),
+		listCmd(),
 		runCmd(),
+		triggerCmd(),
 		updateCmd(),
+		waitCmd(),
 	)
 }
 
@@ -2,16 +2,38 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
 	ecsdeploy "github.com/h3poteto/ecs-goploy/deploy"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 type updateScheduledTask struct {
-	name           string
-	taskDefinition string
-	count          int64
+	name              string
+	taskDefinition    string
+	count             int64
+	launchType        string
+	capacityProviders []string
+	platformVersion   string
+	subnets           string
+	securityGroups    string
+	assignPublicIP    string
+	targetIDs         []string
+	family            string
+	anyFamily         bool
+	backend           string
+	scheduleGroup     string
+	eventBus          string
+	containerName     string
+	command           string
+	env               []string
+	notifiers         notifiers
 }
 
 func updateScheduledTaskCmd() *cobra.Command {
@@ -25,12 +47,256 @@ func updateScheduledTaskCmd() *cobra.Command {
 	flags := command.Flags()
 	flags.StringVarP(&t.name, "name", "n", "", "Name of scheduled task")
 	flags.StringVarP(&t.taskDefinition, "task-definition", "d", "", "Name of task definition to update scheduled task. Family and revision (family:revision) or full ARN")
-	flags.Int64VarP(&t.count, "count", "c", 1, "Count of the task")
+	flags.Int64VarP(&t.count, "count", "c", 0, "Count of the task. Default is 0, and keep the current count")
+	flags.StringVar(&t.launchType, "launch-type", "", "EC2 or FARGATE. Default is none, and keep the current launch type")
+	flags.StringArrayVar(&t.capacityProviders, "capacity-provider", []string{}, "Capacity provider strategy (name:weight:base) to run the task, ex: FARGATE_SPOT:1. This flag can be repeated. Default is none, and keep the current strategy")
+	flags.StringVar(&t.platformVersion, "platform-version", "", "Platform version of Fargate to run the task, ex: LATEST. Default is none, and keep the current version")
+	flags.StringVar(&t.subnets, "subnets", "", "Provide subnet IDs with comma-separated string (subnet-12abcde,subnet-34abcde). Default is none, and keep the current subnets")
+	flags.StringVarP(&t.securityGroups, "security-groups", "g", "", "Provide security group IDs with comma-separated string (sg-0123asdb,sg-2345asdf). Default is none, and keep the current security groups")
+	flags.StringVar(&t.assignPublicIP, "assign-public-ip", "", "Whether the ENI of the task receives a public IP address, ENABLED or DISABLED. Default is none, and keep the current setting")
+	flags.StringArrayVar(&t.targetIDs, "target-id", []string{}, "ID of the target to update. This flag can be repeated. Default is none, and update all ECS targets of the rule")
+	flags.StringVar(&t.family, "family", "", "Task definition family of the targets to update. Default is none, and use the family of the new task definition")
+	flags.BoolVar(&t.anyFamily, "any-family", false, "Update ECS targets regardless of the task definition family")
+	flags.StringVar(&t.backend, "backend", "", "events (EventBridge rule) or scheduler (EventBridge Scheduler). Default is none, and detect it from the name")
+	flags.StringVar(&t.scheduleGroup, "schedule-group", "", "Schedule group of EventBridge Scheduler. Default is none, and use the default group")
+	flags.StringVar(&t.eventBus, "event-bus", "", "Name or ARN of the event bus of scheduled tasks. Default is none, and use the default event bus")
+	flags.StringVar(&t.containerName, "container-name", "", "Name of the container for override task definition. Default is none, and keep the current overrides")
+	flags.StringVar(&t.command, "command", "", "Task command which run on ECS")
+	flags.StringArrayVarP(&t.env, "env", "e", []string{}, "Environment variable (KEY=VALUE) for the container. This flag can be repeated")
+	t.notifiers.addFlags(command)
 
 	return command
 }
 
 func (s *updateScheduledTask) update(cmd *cobra.Command, args []string) error {
+	var baseTaskDefinition *string
+	if len(s.taskDefinition) > 0 {
+		baseTaskDefinition = &s.taskDefinition
+	}
+	profile, region, verbose := generalConfig()
+	if !verbose {
+		log.SetLevel(log.ErrorLevel)
+	}
+	strategy, err := ecsdeploy.ParseCapacityProviderStrategy(s.capacityProviders)
+	if err != nil {
+		log.Fatal(err)
+		return err
+	}
+	scheduledTask := ecsdeploy.NewScheduledTask(profile, region, verbose)
+	scheduledTask.LaunchType = s.launchType
+	scheduledTask.Observers = progressObservers()
+	scheduledTask.Notifiers = s.notifiers.build()
+	scheduledTask.CapacityProviderStrategy = strategy
+	if len(s.platformVersion) > 0 {
+		scheduledTask.PlatformVersion = aws.String(s.platformVersion)
+	}
+	scheduledTask.Subnets = splitIDs(s.subnets)
+	scheduledTask.SecurityGroups = splitIDs(s.securityGroups)
+	scheduledTask.AssignPublicIP = s.assignPublicIP
+	scheduledTask.TargetIDs = s.targetIDs
+	scheduledTask.Family = s.family
+	scheduledTask.AnyFamily = s.anyFamily
+	scheduledTask.Backend = s.backend
+	scheduledTask.ScheduleGroup = s.scheduleGroup
+	scheduledTask.EventBusName = s.eventBus
+	if len(s.containerName) > 0 {
+		override, err := ecsdeploy.NewContainerOverride(s.containerName, s.command, s.env)
+		if err != nil {
+			log.Fatal(err)
+			return err
+		}
+		scheduledTask.ContainerOverrides = []*ecs.ContainerOverride{override}
+	}
+	result, err := scheduledTask.UpdateWithResult(s.name, baseTaskDefinition, s.count)
+	if outputJSON() && result != nil {
+		if err := printJSON(newScheduledTaskOutput(result)); err != nil {
+			log.Fatal(err)
+			return err
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+		return err
+	}
+	if !outputJSON() {
+		fmt.Println("Success to update the schedule")
+	}
+	return nil
+}
+
+type createScheduledTask struct {
+	name               string
+	description        string
+	scheduleExpression string
+	targetID           string
+	cluster            string
+	roleArn            string
+	taskDefinition     string
+	count              int64
+	fargate            bool
+	capacityProviders  []string
+	platformVersion    string
+	subnets            string
+	securityGroups     string
+	assignPublicIP     string
+	containerName      string
+	command            string
+	env                []string
+	eventBus           string
+}
+
+func createScheduledTaskCmd() *cobra.Command {
+	t := &createScheduledTask{}
+	command := &cobra.Command{
+		Use:   "scheduled-task",
+		Short: "Create or update ECS Scheduled Task with the schedule",
+		RunE:  t.create,
+	}
+
+	flags := command.Flags()
+	flags.StringVarP(&t.name, "name", "n", "", "Name of scheduled task")
+	flags.StringVar(&t.description, "description", "", "Description of scheduled task")
+	flags.StringVarP(&t.scheduleExpression, "schedule", "s", "", "Schedule expression, cron() or rate(), ex: \"cron(0 12 * * ? *)\"")
+	flags.StringVar(&t.targetID, "target-id", "", "ID of the target in the rule. Default is the name of scheduled task")
+	flags.StringVar(&t.cluster, "cluster", "", "Name or ARN of ECS cluster")
+	flags.StringVar(&t.roleArn, "role-arn", "", "ARN of IAM role which CloudWatch Events uses to run the task")
+	flags.StringVarP(&t.taskDefinition, "task-definition", "d", "", "Name of task definition of scheduled task. Family and revision (family:revision) or full ARN")
+	flags.Int64VarP(&t.count, "count", "c", 1, "Count of the task")
+	flags.BoolVarP(&t.fargate, "fargate", "f", false, "Whether run task with FARGATE")
+	flags.StringArrayVar(&t.capacityProviders, "capacity-provider", []string{}, "Capacity provider strategy (name:weight:base) to run the task, ex: FARGATE_SPOT:1. This flag can be repeated, and launch type is ignored if this is set")
+	flags.StringVar(&t.platformVersion, "platform-version", "", "Platform version of Fargate to run the task, ex: LATEST")
+	flags.StringVar(&t.subnets, "subnets", "", "Provide subnet IDs with comma-separated string (subnet-12abcde,subnet-34abcde). This param is necessary for awsvpc network mode")
+	flags.StringVarP(&t.securityGroups, "security-groups", "g", "", "Provide security group IDs with comma-separated string (sg-0123asdb,sg-2345asdf), if you want to attach the security groups to ENI of the task")
+	flags.StringVar(&t.assignPublicIP, "assign-public-ip", "", "Whether the ENI of the task receives a public IP address, ENABLED or DISABLED. Default is none, and use DISABLED")
+	flags.StringVar(&t.containerName, "container-name", "", "Name of the container for override task definition")
+	flags.StringVar(&t.command, "command", "", "Task command which run on ECS")
+	flags.StringArrayVarP(&t.env, "env", "e", []string{}, "Environment variable (KEY=VALUE) for the container. This flag can be repeated")
+	flags.StringVar(&t.eventBus, "event-bus", "", "Name or ARN of the event bus of scheduled tasks. Default is none, and use the default event bus")
+
+	return command
+}
+
+func (s *createScheduledTask) create(cmd *cobra.Command, args []string) error {
+	profile, region, verbose := generalConfig()
+	if !verbose {
+		log.SetLevel(log.ErrorLevel)
+	}
+	strategy, err := ecsdeploy.ParseCapacityProviderStrategy(s.capacityProviders)
+	if err != nil {
+		log.Fatal(err)
+		return err
+	}
+	launchType := "EC2"
+	if s.fargate {
+		launchType = "FARGATE"
+	}
+	rule := &ecsdeploy.ScheduledTaskRule{
+		Name:                     s.name,
+		Description:              s.description,
+		ScheduleExpression:       s.scheduleExpression,
+		TargetID:                 s.targetID,
+		Cluster:                  s.cluster,
+		RoleArn:                  s.roleArn,
+		TaskDefinition:           s.taskDefinition,
+		TaskCount:                s.count,
+		LaunchType:               launchType,
+		CapacityProviderStrategy: strategy,
+		PlatformVersion:          s.platformVersion,
+		Subnets:                  splitIDs(s.subnets),
+		SecurityGroups:           splitIDs(s.securityGroups),
+		AssignPublicIP:           s.assignPublicIP,
+	}
+	if len(s.containerName) > 0 {
+		override, err := ecsdeploy.NewContainerOverride(s.containerName, s.command, s.env)
+		if err != nil {
+			log.Fatal(err)
+			return err
+		}
+		rule.ContainerOverrides = []*ecs.ContainerOverride{override}
+	}
+	scheduledTask := ecsdeploy.NewScheduledTask(profile, region, verbose)
+	scheduledTask.EventBusName = s.eventBus
+	if err := scheduledTask.Create(rule); err != nil {
+		log.Fatal(err)
+		return err
+	}
+	fmt.Println("Success to create the schedule")
+	return nil
+}
+
+type deleteScheduledTask struct {
+	name     string
+	eventBus string
+}
+
+func deleteScheduledTaskCmd() *cobra.Command {
+	t := &deleteScheduledTask{}
+	command := &cobra.Command{
+		Use:   "scheduled-task",
+		Short: "Delete ECS Scheduled Task with all targets",
+		RunE:  t.delete,
+	}
+
+	flags := command.Flags()
+	flags.StringVarP(&t.name, "name", "n", "", "Name of scheduled task")
+	flags.StringVar(&t.eventBus, "event-bus", "", "Name or ARN of the event bus of scheduled tasks. Default is none, and use the default event bus")
+
+	return command
+}
+
+func (s *deleteScheduledTask) delete(cmd *cobra.Command, args []string) error {
+	profile, region, verbose := generalConfig()
+	if !verbose {
+		log.SetLevel(log.ErrorLevel)
+	}
+	scheduledTask := ecsdeploy.NewScheduledTask(profile, region, verbose)
+	scheduledTask.EventBusName = s.eventBus
+	if err := scheduledTask.Delete(s.name); err != nil {
+		log.Fatal(err)
+		return err
+	}
+	fmt.Println("Success to delete the schedule")
+	return nil
+}
+
+// splitIDs converts comma-separated IDs to a slice.
+func splitIDs(ids string) []*string {
+	result := []*string{}
+	for _, id := range strings.Split(ids, ",") {
+		if len(id) > 0 {
+			result = append(result, aws.String(id))
+		}
+	}
+	return result
+}
+
+type updateScheduledTasks struct {
+	family         string
+	taskDefinition string
+	count          int64
+	namePrefix     string
+	eventBus       string
+}
+
+func updateScheduledTasksCmd() *cobra.Command {
+	t := &updateScheduledTasks{}
+	command := &cobra.Command{
+		Use:   "scheduled-tasks",
+		Short: "Update all ECS Scheduled Tasks which run the task definition family",
+		RunE:  t.update,
+	}
+
+	flags := command.Flags()
+	flags.StringVar(&t.family, "family", "", "Task definition family of scheduled tasks to update. Default is none, and use the family of the task definition")
+	flags.StringVarP(&t.taskDefinition, "task-definition", "d", "", "Name of task definition to update scheduled tasks. Family and revision (family:revision) or full ARN")
+	flags.Int64VarP(&t.count, "count", "c", 0, "Count of the task. Default is 0, and keep the current count of each scheduled task")
+	flags.StringVar(&t.namePrefix, "name-prefix", "", "Update only scheduled tasks whose name starts with the prefix")
+	flags.StringVar(&t.eventBus, "event-bus", "", "Name or ARN of the event bus of scheduled tasks. Default is none, and use the default event bus")
+
+	return command
+}
+
+func (s *updateScheduledTasks) update(cmd *cobra.Command, args []string) error {
 	var baseTaskDefinition *string
 	if len(s.taskDefinition) > 0 {
 		baseTaskDefinition = &s.taskDefinition
@@ -40,11 +306,210 @@ func (s *updateScheduledTask) update(cmd *cobra.Command, args []string) error {
 		log.SetLevel(log.ErrorLevel)
 	}
 	scheduledTask := ecsdeploy.NewScheduledTask(profile, region, verbose)
-	err := scheduledTask.Update(s.name, baseTaskDefinition, s.count)
+	scheduledTask.EventBusName = s.eventBus
+	scheduledTask.Observers = progressObservers()
+	results, err := scheduledTask.UpdateAll(s.family, baseTaskDefinition, s.count, s.namePrefix)
+	if outputJSON() {
+		outputs := []*scheduledTaskOutput{}
+		for _, r := range results {
+			outputs = append(outputs, newScheduledTaskOutput(r))
+		}
+		if err := printJSON(outputs); err != nil {
+			log.Fatal(err)
+			return err
+		}
+	} else {
+		printScheduledTaskResults(results)
+	}
 	if err != nil {
 		log.Fatal(err)
 		return err
 	}
-	fmt.Println("Success to update the schedule")
+	if !outputJSON() {
+		fmt.Printf("Success to update %d schedules\n", len(results))
+	}
+	return nil
+}
+
+// printScheduledTaskResults prints a summary of the updated rules.
+func printScheduledTaskResults(results []*ecsdeploy.ScheduledTaskResult) {
+	if len(results) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "RULE\tSCHEDULE\tTARGETS\tPREVIOUS TASK DEFINITION\tRESULT")
+	for _, r := range results {
+		result := "updated"
+		if r.Err != nil {
+			result = r.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Rule, r.ScheduleExpression, strings.Join(r.TargetIDs, ","), r.PreviousTaskDefinitionArn, result)
+	}
+	w.Flush()
+}
+
+type changeScheduledTaskState struct {
+	name     string
+	enable   bool
+	eventBus string
+}
+
+func enableScheduledTaskCmd() *cobra.Command {
+	t := &changeScheduledTaskState{enable: true}
+	command := &cobra.Command{
+		Use:   "scheduled-task",
+		Short: "Enable ECS Scheduled Task",
+		RunE:  t.change,
+	}
+
+	flags := command.Flags()
+	flags.StringVarP(&t.name, "name", "n", "", "Name of scheduled task")
+	flags.StringVar(&t.eventBus, "event-bus", "", "Name or ARN of the event bus of scheduled tasks. Default is none, and use the default event bus")
+
+	return command
+}
+
+func disableScheduledTaskCmd() *cobra.Command {
+	t := &changeScheduledTaskState{enable: false}
+	command := &cobra.Command{
+		Use:   "scheduled-task",
+		Short: "Disable ECS Scheduled Task",
+		RunE:  t.change,
+	}
+
+	flags := command.Flags()
+	flags.StringVarP(&t.name, "name", "n", "", "Name of scheduled task")
+	flags.StringVar(&t.eventBus, "event-bus", "", "Name or ARN of the event bus of scheduled tasks. Default is none, and use the default event bus")
+
+	return command
+}
+
+func (s *changeScheduledTaskState) change(cmd *cobra.Command, args []string) error {
+	profile, region, verbose := generalConfig()
+	if !verbose {
+		log.SetLevel(log.ErrorLevel)
+	}
+	scheduledTask := ecsdeploy.NewScheduledTask(profile, region, verbose)
+	scheduledTask.EventBusName = s.eventBus
+	if s.enable {
+		if err := scheduledTask.EnableRule(s.name); err != nil {
+			log.Fatal(err)
+			return err
+		}
+		fmt.Println("Success to enable the schedule")
+		return nil
+	}
+	if err := scheduledTask.DisableRule(s.name); err != nil {
+		log.Fatal(err)
+		return err
+	}
+	fmt.Println("Success to disable the schedule")
 	return nil
 }
+
+type triggerScheduledTask struct {
+	name      string
+	targetIDs []string
+	timeout   int
+	eventBus  string
+}
+
+func triggerScheduledTaskCmd() *cobra.Command {
+	t := &triggerScheduledTask{}
+	command := &cobra.Command{
+		Use:   "scheduled-task",
+		Short: "Run ECS Scheduled Task immediately",
+		RunE:  t.trigger,
+	}
+
+	flags := command.Flags()
+	flags.StringVarP(&t.name, "name", "n", "", "Name of scheduled task")
+	flags.StringArrayVar(&t.targetIDs, "target-id", []string{}, "ID of the target to run. This flag can be repeated. Default is none, and run all ECS targets of the rule")
+	flags.IntVarP(&t.timeout, "timeout", "t", 0, "Timeout seconds")
+	flags.StringVar(&t.eventBus, "event-bus", "", "Name or ARN of the event bus of scheduled tasks. Default is none, and use the default event bus")
+
+	return command
+}
+
+func (s *triggerScheduledTask) trigger(cmd *cobra.Command, args []string) error {
+	profile, region, verbose := generalConfig()
+	if !verbose {
+		log.SetLevel(log.ErrorLevel)
+	}
+	scheduledTask := ecsdeploy.NewScheduledTask(profile, region, verbose)
+	scheduledTask.TargetIDs = s.targetIDs
+	scheduledTask.EventBusName = s.eventBus
+	scheduledTask.Observers = progressObservers()
+	startedAt := time.Now()
+	tasks, err := scheduledTask.Trigger(s.name, (time.Duration(s.timeout) * time.Second))
+	cluster := ""
+	if len(tasks) > 0 {
+		cluster = aws.StringValue(tasks[0].ClusterArn)
+	}
+	printStoppedTasks(cluster, tasks, time.Since(startedAt), err)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !outputJSON() {
+		fmt.Println("Success to run the schedule")
+	}
+	return nil
+}
+
+type listScheduledTasks struct {
+	namePrefix string
+	eventBus   string
+}
+
+func listScheduledTasksCmd() *cobra.Command {
+	t := &listScheduledTasks{}
+	command := &cobra.Command{
+		Use:   "scheduled-tasks",
+		Short: "List ECS Scheduled Tasks with the current task definition and the last run",
+		RunE:  t.list,
+	}
+
+	flags := command.Flags()
+	flags.StringVar(&t.namePrefix, "name-prefix", "", "List only scheduled tasks whose name starts with the prefix")
+	flags.StringVar(&t.eventBus, "event-bus", "", "Name or ARN of the event bus of scheduled tasks. Default is none, and use the default event bus")
+
+	return command
+}
+
+func (s *listScheduledTasks) list(cmd *cobra.Command, args []string) error {
+	profile, region, verbose := generalConfig()
+	if !verbose {
+		log.SetLevel(log.ErrorLevel)
+	}
+	scheduledTask := ecsdeploy.NewScheduledTask(profile, region, verbose)
+	scheduledTask.EventBusName = s.eventBus
+	summaries, err := scheduledTask.List(s.namePrefix)
+	if err != nil {
+		log.Fatal(err)
+		return err
+	}
+	if outputJSON() {
+		return printJSON(summaries)
+	}
+	printScheduledTaskSummaries(summaries)
+	return nil
+}
+
+func printScheduledTaskSummaries(summaries []*ecsdeploy.ScheduledTaskSummary) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "RULE\tSCHEDULE\tSTATE\tTARGET\tCLUSTER\tTASK DEFINITION\tCOUNT\tLAST RUN")
+	for _, s := range summaries {
+		lastRun := "-"
+		if s.LastRunAt != nil {
+			lastRun = s.LastRunStatus + " (" + s.LastRunAt.Format(time.RFC3339) + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n", s.Rule, s.ScheduleExpression, s.State, s.TargetID, lastPart(s.Cluster), lastPart(s.TaskDefinition), s.TaskCount, lastRun)
+	}
+	w.Flush()
+}
+
+// lastPart returns the resource name of ARN, for example family:revision of the task definition.
+func lastPart(arn string) string {
+	parts := strings.Split(arn, "/")
+	return parts[len(parts)-1]
+}
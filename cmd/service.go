@@ -1,14 +1,20 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
 	ecsdeploy "github.com/h3poteto/ecs-goploy/deploy"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+// lockTTLMargin is added to the timeout of the deploy as TTL of the lock.
+const lockTTLMargin = 5 * time.Minute
+
 type updateService struct {
 	cluster              string
 	name                 string
@@ -17,6 +23,13 @@ type updateService struct {
 	timeout              int
 	enableRollback       bool
 	skipCheckDeployments bool
+	capacityProviders    []string
+	platformVersion      string
+	lockTable            string
+	waitForLock          int
+	force                bool
+	hooks                hooks
+	notifiers            notifiers
 }
 
 func updateServiceCmd() *cobra.Command {
@@ -35,6 +48,13 @@ func updateServiceCmd() *cobra.Command {
 	flags.IntVarP(&s.timeout, "timeout", "t", 300, "Timeout seconds. Script monitors ECS Service for new task definition to be running")
 	flags.BoolVar(&s.enableRollback, "enable-rollback", false, "Rollback task definition if new version is not running before TIMEOUT")
 	flags.BoolVar(&s.skipCheckDeployments, "skip-check-deployments", false, "Skip checking deployments when detect whether deploy completed")
+	flags.StringArrayVar(&s.capacityProviders, "capacity-provider", []string{}, "Capacity provider strategy (name:weight:base) for the service, ex: FARGATE_SPOT:1. This flag can be repeated. Default is none, and keep the current strategy")
+	flags.StringVar(&s.platformVersion, "platform-version", "", "Platform version of Fargate for the service, ex: LATEST")
+	flags.StringVar(&s.lockTable, "lock-table", "", "DynamoDB table to lock the service while deploying. Default is none, and refuse to deploy while the service has more than one deployment")
+	flags.IntVar(&s.waitForLock, "wait-for-lock", 0, "Seconds to wait for the lock held by another deploy. Default is 0, and fail immediately")
+	flags.BoolVar(&s.force, "force", false, "Deploy without the lock even if another deploy is in progress")
+	s.hooks.addFlags(cmd)
+	s.notifiers.addFlags(cmd)
 
 	return cmd
 }
@@ -52,8 +72,74 @@ func (s *updateService) update(cmd *cobra.Command, args []string) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	if err := service.Deploy(); err != nil {
+	strategy, err := ecsdeploy.ParseCapacityProviderStrategy(s.capacityProviders)
+	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println("Deploy success")
+	service.CapacityProviderStrategy = strategy
+	service.Observers = progressObservers()
+	service.Notifiers = s.notifiers.build()
+	if !s.force {
+		if len(s.lockTable) > 0 {
+			locker := ecsdeploy.NewDynamoDBLocker(s.lockTable, ecsdeploy.WithProfile(profile), ecsdeploy.WithRegion(region))
+			// The lock must not expire while the deploy waits for the new task.
+			locker.TTL = service.Timeout + lockTTLMargin
+			service.Locker = locker
+		} else {
+			service.Locker = ecsdeploy.NewECSDeploymentLocker(ecsdeploy.WithProfile(profile), ecsdeploy.WithRegion(region))
+		}
+		service.LockTimeout = time.Duration(s.waitForLock) * time.Second
+	}
+	if len(s.platformVersion) > 0 {
+		service.PlatformVersion = &s.platformVersion
+	}
+	env := &ecsdeploy.HookEnvironment{
+		Cluster: s.cluster,
+		Service: s.name,
+		Image:   s.imageWithTag,
+	}
+	// The pre-hook runs after the service is locked, so it does not run for the deploy which is refused.
+	var preErr error
+	service.PreDeploy = func(ctx context.Context, current *ecs.Service) error {
+		env.PreviousTaskDefinitionArn = aws.StringValue(current.TaskDefinition)
+		preErr = s.hooks.runPre(env)
+		return preErr
+	}
+	result, err := service.DeployWithResult()
+	if preErr != nil {
+		log.Fatal(preErr)
+	}
+	hookErr := s.hooks.runAfter(deployHookEnvironment(env, result, err))
+	if outputJSON() {
+		if err := printJSON(newDeployOutput(s.cluster, s.name, result, err)); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if hookErr != nil {
+		log.Fatal(hookErr)
+	}
+	if !outputJSON() {
+		fmt.Println("Deploy success")
+	}
+}
+
+// deployHookEnvironment sets the result of the deploy to the environment of the hook.
+func deployHookEnvironment(env *ecsdeploy.HookEnvironment, result *ecsdeploy.DeployResult, err error) *ecsdeploy.HookEnvironment {
+	if result != nil {
+		env.TaskDefinitionArn = result.TaskDefinitionArn
+		env.PreviousTaskDefinitionArn = result.PreviousTaskDefinitionArn
+		env.DeploymentID = result.DeploymentID
+	}
+	switch {
+	case err == nil:
+		env.Status = ecsdeploy.StatusSucceeded
+	case result != nil && result.RolledBack:
+		env.Status = ecsdeploy.StatusRolledBack
+	default:
+		env.Status = ecsdeploy.StatusFailed
+	}
+	return env
 }
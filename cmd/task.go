@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
 	ecsdeploy "github.com/h3poteto/ecs-goploy/deploy"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +27,37 @@ type runTask struct {
 	securityGroups string
 	fargate        bool
 	timeout        int
+
+	env              []string
+	containerCPU     int64
+	containerMemory  int64
+	cpu              string
+	memory           string
+	taskRoleArn      string
+	executionRoleArn string
+	overrides        []string
+	overrideEnvs     []string
+
+	capacityProviders  []string
+	platformVersion    string
+	networkFromService string
+	assignPublicIP     string
+
+	count       int64
+	shards      int
+	concurrency int
+	shardEnv    string
+
+	placementConstraints []string
+	placementStrategy    []string
+	tags                 []string
+	propagateTags        string
+	startedBy            string
+	group                string
+	detach               bool
+
+	hooks     hooks
+	notifiers notifiers
 }
 
 func runTaskCmd() *cobra.Command {
@@ -37,7 +76,33 @@ func runTaskCmd() *cobra.Command {
 	flags.StringVarP(&t.subnets, "subnets", "s", "", "Provide subnet IDs with comma-separated string (subnet-12abcde,subnet-34abcde). This param is necessary, if you set farage flag.")
 	flags.StringVarP(&t.securityGroups, "security-groups", "g", "", "Provide security group IDs with comma-separated string (sg-0123asdb,sg-2345asdf), if you want to attach the security groups to ENI of the task.")
 	flags.BoolVarP(&t.fargate, "fargate", "f", false, "Whether run task with FARGATE")
+	flags.StringVar(&t.assignPublicIP, "assign-public-ip", "", "Whether the ENI of the task receives a public IP address, ENABLED or DISABLED. ENABLED is supported only on FARGATE. Default is none, and use DISABLED")
 	flags.IntVarP(&t.timeout, "timeout", "t", 0, "Timeout seconds")
+	flags.BoolVar(&t.detach, "detach", false, "Do not wait the task, and print the task ARNs as JSON. Please use wait task command to wait the task")
+	t.hooks.addFlags(cmd)
+	t.notifiers.addFlags(cmd)
+	flags.Int64Var(&t.count, "count", 1, "Number of tasks to run at once, from 1 to 10")
+	flags.IntVar(&t.shards, "shards", 0, "Run the task as a batch of shards. Each shard runs one task, and receives the shard index as an environment variable")
+	flags.IntVar(&t.concurrency, "concurrency", 10, "Maximum number of shards which run at the same time")
+	flags.StringVar(&t.shardEnv, "shard-env", "SHARD_INDEX", "Name of the environment variable which passes the shard index to the container")
+	flags.StringArrayVarP(&t.env, "env", "e", []string{}, "Environment variable (KEY=VALUE) for the container. This flag can be repeated")
+	flags.Int64Var(&t.containerCPU, "container-cpu", 0, "Number of cpu units reserved for the container")
+	flags.Int64Var(&t.containerMemory, "container-memory", 0, "Hard limit (in MiB) of memory for the container")
+	flags.StringVar(&t.cpu, "cpu", "", "Task-level cpu units which override the task definition")
+	flags.StringVar(&t.memory, "memory", "", "Task-level memory (in MiB) which overrides the task definition")
+	flags.StringVar(&t.taskRoleArn, "task-role-arn", "", "ARN of IAM role which the containers in the task can assume")
+	flags.StringVar(&t.executionRoleArn, "execution-role-arn", "", "ARN of IAM role which the container agent uses to pull images and publish logs")
+	flags.StringArrayVar(&t.overrides, "override", []string{}, "Command for another container in the task definition (NAME=COMMAND). This flag can be repeated")
+	flags.StringArrayVar(&t.overrideEnvs, "override-env", []string{}, "Environment variable for another container in the task definition (NAME:KEY=VALUE). This flag can be repeated")
+	flags.StringArrayVar(&t.capacityProviders, "capacity-provider", []string{}, "Capacity provider strategy (name:weight:base) to run the task, ex: FARGATE_SPOT:1. This flag can be repeated, and launch type is ignored if this is set")
+	flags.StringVar(&t.platformVersion, "platform-version", "", "Platform version of Fargate to run the task, ex: LATEST")
+	flags.StringArrayVar(&t.placementConstraints, "placement-constraint", []string{}, "Placement constraint (type:expression) of the task, ex: memberOf:attribute:ecs.instance-type =~ t3.*. This flag can be repeated")
+	flags.StringArrayVar(&t.placementStrategy, "placement-strategy", []string{}, "Placement strategy (type:field) of the task, ex: spread:attribute:ecs.availability-zone. This flag can be repeated")
+	flags.StringArrayVar(&t.tags, "tag", []string{}, "Tag (KEY=VALUE) which is attached to the task. This flag can be repeated")
+	flags.StringVar(&t.propagateTags, "propagate-tags", "", "Propagate tags from TASK_DEFINITION to the task")
+	flags.StringVar(&t.startedBy, "started-by", "ecs-goploy", "Value of startedBy of the task")
+	flags.StringVar(&t.group, "group", "", "Task group name of the task")
+	flags.StringVar(&t.networkFromService, "network-from-service", "", "Service (cluster/service, or service in the cluster) to inherit subnets, security groups, launch type and capacity providers")
 
 	return cmd
 }
@@ -51,8 +116,304 @@ func (t *runTask) run(cmd *cobra.Command, args []string) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	if _, err := task.Run(); err != nil {
+	if err := t.setOverrides(task); err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println("Success to run task")
+	strategy, err := ecsdeploy.ParseCapacityProviderStrategy(t.capacityProviders)
+	if err != nil {
+		log.Fatal(err)
+	}
+	task.CapacityProviderStrategy = strategy
+	task.NetworkFromService = t.networkFromService
+	task.AssignPublicIP = t.assignPublicIP
+	if len(t.platformVersion) > 0 {
+		task.PlatformVersion = aws.String(t.platformVersion)
+	}
+	if t.count < 1 || t.count > 10 {
+		log.Fatal("count must be from 1 to 10")
+	}
+	task.Count = t.count
+	if err := t.setPlacementAndTags(task); err != nil {
+		log.Fatal(err)
+	}
+	task.ShardEnvironment = t.shardEnv
+	task.Observers = progressObservers()
+	task.Notifiers = t.notifiers.build()
+	if t.detach && t.shards > 0 {
+		log.Fatal("detach can not be used with shards")
+	}
+	if t.count > 1 && t.shards > 0 {
+		log.Fatal("count can not be used with shards")
+	}
+
+	env := &ecsdeploy.HookEnvironment{
+		Cluster:           t.cluster,
+		TaskDefinitionArn: t.taskDefinition,
+	}
+	if err := t.hooks.runPre(env); err != nil {
+		log.Fatal(err)
+	}
+	if t.detach {
+		// The tasks are only started, so the post-hook and the on-failure-hook do not run.
+		tasks, err := task.Start()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := printDetachedTasks(t.cluster, tasks); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	startedAt := time.Now()
+	var results []*ecsdeploy.TaskResult
+	if t.shards > 0 {
+		results, err = task.RunShards(t.shards, t.concurrency)
+	} else {
+		var tasks []*ecs.Task
+		tasks, err = task.Run()
+		results = ecsdeploy.NewTaskResults(tasks)
+	}
+	hookErr := t.hooks.runAfter(taskHookEnvironment(env, err))
+	t.printResults(results, t.shards > 0, time.Since(startedAt), err)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if hookErr != nil {
+		log.Fatal(hookErr)
+	}
+	if !outputJSON() {
+		fmt.Println("Success to run task")
+	}
+}
+
+// taskHookEnvironment sets the status of the task to the environment of the hook.
+func taskHookEnvironment(env *ecsdeploy.HookEnvironment, err error) *ecsdeploy.HookEnvironment {
+	env.Status = ecsdeploy.StatusSucceeded
+	if err != nil {
+		env.Status = ecsdeploy.StatusFailed
+	}
+	return env
+}
+
+// printResults prints results of the tasks as JSON or a table.
+// The table is printed only when multiple tasks run.
+func (t *runTask) printResults(results []*ecsdeploy.TaskResult, batch bool, duration time.Duration, err error) {
+	if outputJSON() {
+		o := &runTaskOutput{
+			Cluster:         t.cluster,
+			DurationSeconds: duration.Seconds(),
+			Tasks:           newTaskResultOutputs(results, batch),
+			Error:           errorMessage(err),
+		}
+		if err := printJSON(o); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if batch || t.count > 1 {
+		printTaskResults(results, batch)
+	}
+}
+
+// detachedTasks is a JSON document of the detached tasks.
+type detachedTasks struct {
+	Cluster  string   `json:"cluster"`
+	TaskArns []string `json:"taskArns"`
+}
+
+// printDetachedTasks prints ARNs of the started tasks as JSON.
+func printDetachedTasks(cluster string, tasks []*ecs.Task) error {
+	d := detachedTasks{
+		Cluster:  cluster,
+		TaskArns: []string{},
+	}
+	for _, task := range tasks {
+		d.TaskArns = append(d.TaskArns, aws.StringValue(task.TaskArn))
+	}
+	return printJSON(&d)
+}
+
+type waitTask struct {
+	cluster string
+	tasks   []string
+	timeout int
+}
+
+func waitTaskCmd() *cobra.Command {
+	t := &waitTask{}
+	cmd := &cobra.Command{
+		Use:   "task",
+		Short: "Wait tasks on ECS to stop",
+		Run:   t.wait,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&t.cluster, "cluster", "c", "", "Name of ECS cluster")
+	flags.StringArrayVar(&t.tasks, "task", []string{}, "ARN of the task to wait. This flag can be repeated")
+	flags.IntVarP(&t.timeout, "timeout", "t", 0, "Timeout seconds")
+
+	return cmd
+}
+
+func (t *waitTask) wait(cmd *cobra.Command, args []string) {
+	profile, region, verbose := generalConfig()
+	if !verbose {
+		log.SetLevel(log.ErrorLevel)
+	}
+	task, err := ecsdeploy.NewTask(t.cluster, "", "", "", false, "", "", (time.Duration(t.timeout) * time.Second), profile, region, verbose)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if t.timeout != 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), task.Timeout)
+	}
+	defer cancel()
+
+	startedAt := time.Now()
+	tasks, err := task.Wait(ctx, append(t.tasks, args...))
+	printStoppedTasks(t.cluster, tasks, time.Since(startedAt), err)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !outputJSON() {
+		fmt.Println("All tasks stopped successfully")
+	}
+}
+
+// printStoppedTasks prints results of the stopped tasks as JSON or a table.
+func printStoppedTasks(cluster string, tasks []*ecs.Task, duration time.Duration, err error) {
+	results := ecsdeploy.NewTaskResults(tasks)
+	if outputJSON() {
+		o := &runTaskOutput{
+			Cluster:         cluster,
+			DurationSeconds: duration.Seconds(),
+			Tasks:           newTaskResultOutputs(results, false),
+			Error:           errorMessage(err),
+		}
+		if err := printJSON(o); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	printTaskResults(results, false)
+}
+
+// printTaskResults prints a table of the task results.
+func printTaskResults(results []*ecsdeploy.TaskResult, batch bool) {
+	if len(results) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	if batch {
+		fmt.Fprint(w, "SHARD\t")
+	}
+	fmt.Fprintln(w, "TASK ARN\tEXIT CODE\tDURATION\tSTOPPED REASON")
+	for _, r := range results {
+		if batch {
+			fmt.Fprintf(w, "%d\t", r.Shard)
+		}
+		exitCode := "-"
+		if r.ExitCode != nil {
+			exitCode = strconv.FormatInt(*r.ExitCode, 10)
+		}
+		reason := r.StoppedReason
+		if r.Err != nil && r.TaskArn == "" {
+			reason = r.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.TaskArn, exitCode, r.Duration.Round(time.Second), reason)
+	}
+	w.Flush()
+}
+
+// setPlacementAndTags sets placement constraints, strategy, tags and group to the task.
+func (t *runTask) setPlacementAndTags(task *ecsdeploy.Task) error {
+	constraints, err := ecsdeploy.ParsePlacementConstraints(t.placementConstraints)
+	if err != nil {
+		return err
+	}
+	task.PlacementConstraints = constraints
+	strategy, err := ecsdeploy.ParsePlacementStrategy(t.placementStrategy)
+	if err != nil {
+		return err
+	}
+	task.PlacementStrategy = strategy
+	tags, err := ecsdeploy.ParseTags(t.tags)
+	if err != nil {
+		return err
+	}
+	task.Tags = tags
+	if len(t.propagateTags) > 0 {
+		task.PropagateTags = aws.String(t.propagateTags)
+	}
+	task.StartedBy = t.startedBy
+	if len(t.group) > 0 {
+		task.Group = aws.String(t.group)
+	}
+	return nil
+}
+
+// setOverrides sets container and task-level overrides to the task.
+func (t *runTask) setOverrides(task *ecsdeploy.Task) error {
+	env, err := ecsdeploy.ParseEnvironment(t.env)
+	if err != nil {
+		return err
+	}
+	task.Environment = env
+	if t.containerCPU > 0 {
+		task.ContainerCPU = aws.Int64(t.containerCPU)
+	}
+	if t.containerMemory > 0 {
+		task.ContainerMemory = aws.Int64(t.containerMemory)
+	}
+	if len(t.cpu) > 0 {
+		task.CPU = aws.String(t.cpu)
+	}
+	if len(t.memory) > 0 {
+		task.Memory = aws.String(t.memory)
+	}
+	if len(t.taskRoleArn) > 0 {
+		task.TaskRoleArn = aws.String(t.taskRoleArn)
+	}
+	if len(t.executionRoleArn) > 0 {
+		task.ExecutionRoleArn = aws.String(t.executionRoleArn)
+	}
+
+	// Keep the order of containers as specified.
+	names := []string{}
+	commands := map[string]string{}
+	envs := map[string][]string{}
+	seen := map[string]bool{}
+	for _, o := range t.overrides {
+		kv := strings.SplitN(o, "=", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			return errors.Errorf("override format is wrong: %s", o)
+		}
+		if !seen[kv[0]] {
+			names = append(names, kv[0])
+			seen[kv[0]] = true
+		}
+		commands[kv[0]] = kv[1]
+	}
+	for _, o := range t.overrideEnvs {
+		kv := strings.SplitN(o, ":", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			return errors.Errorf("override-env format is wrong: %s", o)
+		}
+		if !seen[kv[0]] {
+			names = append(names, kv[0])
+			seen[kv[0]] = true
+		}
+		envs[kv[0]] = append(envs[kv[0]], kv[1])
+	}
+	overrides := []*ecs.ContainerOverride{}
+	for _, name := range names {
+		o, err := ecsdeploy.NewContainerOverride(name, commands[name], envs[name])
+		if err != nil {
+			return err
+		}
+		overrides = append(overrides, o)
+	}
+	task.ContainerOverrides = overrides
+	return nil
 }
@@ -38,11 +38,17 @@ func (n *updateTaskDefinition) update(cmd *cobra.Command, args []string) error {
 		log.SetLevel(log.ErrorLevel)
 	}
 	taskDefinition := ecsdeploy.NewTaskDefinition(profile, region, verbose)
-	t, err := taskDefinition.Create(baseTaskDefinition, n.imageWithTag)
+	result, err := taskDefinition.CreateWithResult(baseTaskDefinition, n.imageWithTag)
 	if err != nil {
 		log.Fatal(err)
 		return err
 	}
-	fmt.Println(*t.TaskDefinitionArn)
-	return nil
+	if !outputJSON() {
+		fmt.Println(result.TaskDefinitionArn)
+		return nil
+	}
+	return printJSON(&taskDefinitionOutput{
+		TaskDefinitionArn:         result.TaskDefinitionArn,
+		PreviousTaskDefinitionArn: result.PreviousTaskDefinitionArn,
+	})
 }
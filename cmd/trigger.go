@@ -0,0 +1,18 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func triggerCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "trigger",
+		Short: "Run some ECS resource immediately",
+		Run: func(c *cobra.Command, arg []string) {
+			c.Help()
+		},
+	}
+	command.AddCommand(
+		triggerScheduledTaskCmd(),
+	)
+
+	return command
+}
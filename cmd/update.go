@@ -15,6 +15,7 @@ func updateCmd() *cobra.Command {
 		updateServiceCmd(),
 		updateTaskDefinitionCmd(),
 		updateScheduledTaskCmd(),
+		updateScheduledTasksCmd(),
 	)
 
 	return command
@@ -0,0 +1,18 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func waitCmd() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait some ECS resource",
+		Run: func(c *cobra.Command, arg []string) {
+			c.Help()
+		},
+	}
+	command.AddCommand(
+		waitTaskCmd(),
+	)
+
+	return command
+}
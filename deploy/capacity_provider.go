@@ -0,0 +1,41 @@
+package deploy
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// ParseCapacityProviderStrategy converts name:weight:base strings to a capacity provider strategy.
+// Weight and base can be omitted, for example FARGATE_SPOT:3 or FARGATE:1:2.
+func ParseCapacityProviderStrategy(providers []string) ([]*ecs.CapacityProviderStrategyItem, error) {
+	var strategy []*ecs.CapacityProviderStrategyItem
+	for _, p := range providers {
+		values := strings.Split(p, ":")
+		if len(values) > 3 || len(values[0]) == 0 {
+			return nil, errors.Errorf("capacity provider format is wrong: %s", p)
+		}
+		item := &ecs.CapacityProviderStrategyItem{
+			CapacityProvider: aws.String(values[0]),
+		}
+		if len(values) > 1 {
+			weight, err := strconv.ParseInt(values[1], 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "weight of capacity provider is wrong: %s", p)
+			}
+			item.Weight = aws.Int64(weight)
+		}
+		if len(values) > 2 {
+			base, err := strconv.ParseInt(values[2], 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "base of capacity provider is wrong: %s", p)
+			}
+			item.Base = aws.Int64(base)
+		}
+		strategy = append(strategy, item)
+	}
+	return strategy, nil
+}
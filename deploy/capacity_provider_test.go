@@ -0,0 +1,28 @@
+package deploy
+
+import (
+	"testing"
+)
+
+func TestParseCapacityProviderStrategy(t *testing.T) {
+	strategy, err := ParseCapacityProviderStrategy([]string{"FARGATE:1:2", "FARGATE_SPOT:3", "my-asg"})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(strategy) != 3 {
+		t.Fatalf("strategy is invalid: %v", strategy)
+	}
+	if *strategy[0].CapacityProvider != "FARGATE" || *strategy[0].Weight != 1 || *strategy[0].Base != 2 {
+		t.Errorf("strategy is invalid: %v", strategy[0])
+	}
+	if *strategy[1].CapacityProvider != "FARGATE_SPOT" || *strategy[1].Weight != 3 || strategy[1].Base != nil {
+		t.Errorf("strategy is invalid: %v", strategy[1])
+	}
+	if *strategy[2].CapacityProvider != "my-asg" || strategy[2].Weight != nil {
+		t.Errorf("strategy is invalid: %v", strategy[2])
+	}
+
+	if _, err := ParseCapacityProviderStrategy([]string{"FARGATE:one"}); err == nil {
+		t.Error("invalid weight should be error")
+	}
+}
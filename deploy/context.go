@@ -0,0 +1,28 @@
+package deploy
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// sleep waits for the duration, and returns an error if the context is done before that.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return contextError(ctx)
+	}
+}
+
+// contextError returns the reason why the context is done.
+func contextError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.New("process timeout")
+	}
+	return ctx.Err()
+}
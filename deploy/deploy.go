@@ -92,16 +92,63 @@ For example:
     scheduledTask := ecsdeploy.NewScheduledTask("", "", true)
     scheduledTask("schedule-name", "sample-task-definition:2", 1)
 
+Cancellation
+
+Each method which calls AWS API has a WithContext variant, for example DeployWithContext, RunWithContext and UpdateWithContext.
+When the context is canceled, API calls and polling stop.
+
+For example:
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Minute)
+    defer cancel()
+    if _, err := s.DeployWithContext(ctx); err != nil {
+        log.Fatal(err)
+    }
+
+Options
+
+NewServiceWithOptions, NewTaskWithOptions, NewTaskDefinitionWithOptions and NewScheduledTaskWithOptions accept functional options.
+WithSession and WithECSClient inject an AWS session or API clients, for example mocks in tests.
+WithLogger injects a logrus entry. The package never changes the global logger of logrus,
+and structured fields like cluster, service and deployment are added to the logger.
+
+    s, err := ecsdeploy.NewServiceWithOptions("cluster", "service-name", ecsdeploy.WithImage("nginx:stable"), ecsdeploy.WithSession(sess))
+
+Events
+
+Service, Task and ScheduledTask send events like EventTaskDefinitionRegistered, EventServiceUpdated and EventRollbackStarted to Observers.
+
+    s.Observers = append(s.Observers, ecsdeploy.ObserverFunc(func(event *ecsdeploy.Event) {
+        fmt.Println(event.Type, event.TaskDefinitionArn)
+    }))
+
+Notifications
+
+Notifiers of Service, Task and ScheduledTask are notified when Deploy, Run and Update start and finish.
+WebhookNotifier posts the notification as JSON, and SlackNotifier posts it to an incoming webhook of Slack.
+
+    s.Notifiers = append(s.Notifiers, &ecsdeploy.SlackNotifier{WebhookURL: "https://hooks.slack.com/services/XXX"})
+
+Locking
+
+Locker of Service prevents concurrent deploys of the same service. DynamoDBLocker locks the service with a conditional put,
+and ECSDeploymentLocker refuses to deploy while the service has more than one deployment.
+
+    s.Locker = ecsdeploy.NewDynamoDBLocker("ecs-goploy-locks")
+    s.LockTimeout = 10 * time.Minute
+
 */
 package deploy
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 )
 
 // Image has repository and tag string of docker image.
@@ -116,49 +163,160 @@ type Image struct {
 
 // Deploy runs deploy commands and handle errors.
 func (s *Service) Deploy() error {
-	service, err := s.DescribeService()
+	_, err := s.DeployWithContext(context.Background())
+	return err
+}
+
+// DeployResult has a result of deploying the service.
+type DeployResult struct {
+	// Name of ECS cluster.
+	Cluster string
+
+	// Name of ECS service.
+	Service string
+
+	// Task definition which is deployed.
+	TaskDefinitionArn string
+
+	// Task definition which the service ran before the deploy.
+	PreviousTaskDefinitionArn string
+
+	// ID of the primary deployment which is created by the deploy.
+	DeploymentID string
+
+	// Duration of the deploy.
+	Duration time.Duration
+
+	// Whether the service was rolled back to the previous task definition.
+	RolledBack bool
+}
+
+// DeployWithResult runs deploy commands as same as Deploy, and returns the result of the deploy.
+// If the deploy fails after the new task definition is registered, the result is returned with the error.
+func (s *Service) DeployWithResult() (*DeployResult, error) {
+	return s.DeployWithContext(context.Background())
+}
+
+// DeployWithContext runs deploy commands with the context, and returns the result of the deploy.
+// If the context is canceled while waiting for the new task, the deploy is stopped and rolled back if EnableRollback is true.
+// If Locker is set, the service is locked while deploying, and ErrLocked is returned when another deploy holds the lock.
+func (s *Service) DeployWithContext(ctx context.Context) (*DeployResult, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.unlock()
+
+	s.sendNotification(&Notification{Status: StatusStarted})
+	result, err := s.deploy(ctx)
+	n := &Notification{
+		Status: StatusSucceeded,
+		Error:  errorString(err),
+	}
+	if result != nil {
+		n.TaskDefinitionArn = result.TaskDefinitionArn
+		n.PreviousTaskDefinitionArn = result.PreviousTaskDefinitionArn
+		n.DeploymentID = result.DeploymentID
+	}
 	if err != nil {
-		return errors.Wrap(err, "Can not get current service: ")
+		n.Status = StatusFailed
+		if result != nil && result.RolledBack {
+			n.Status = StatusRolledBack
+		}
+	}
+	s.sendNotification(n)
+	return result, err
+}
+
+// rollbackTimeout is the timeout of update-service API to roll back the service.
+var rollbackTimeout = time.Minute
+
+// deploy runs deploy commands, and returns the result of the deploy.
+func (s *Service) deploy(ctx context.Context) (*DeployResult, error) {
+	startedAt := time.Now()
+	result := &DeployResult{
+		Cluster: s.Cluster,
+		Service: s.Name,
+	}
+	service, err := s.DescribeServiceWithContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can not get current service: ")
+	}
+	if s.PreDeploy != nil {
+		if err := s.PreDeploy(ctx, service); err != nil {
+			return nil, err
+		}
 	}
 
 	// get running task definition
-	currentTaskDefinition, err := s.TaskDefinition.DescribeTaskDefinition(*service.TaskDefinition)
+	currentTaskDefinition, err := s.TaskDefinition.DescribeTaskDefinitionWithContext(ctx, *service.TaskDefinition)
 	if err != nil {
-		return errors.Wrap(err, "Can not get task definition: ")
+		return nil, errors.Wrap(err, "Can not get task definition: ")
 	}
+	result.PreviousTaskDefinitionArn = aws.StringValue(currentTaskDefinition.TaskDefinitionArn)
 
 	// get base task definition if needed
 	baseTaskDefinition := currentTaskDefinition
 	if s.BaseTaskDefinition != nil {
 		var err error
-		baseTaskDefinition, err = s.TaskDefinition.DescribeTaskDefinition(*s.BaseTaskDefinition)
+		baseTaskDefinition, err = s.TaskDefinition.DescribeTaskDefinitionWithContext(ctx, *s.BaseTaskDefinition)
 		if err != nil {
-			return errors.Wrap(err, "Can not get task definition: ")
+			return nil, errors.Wrap(err, "Can not get task definition: ")
 		}
 	}
 
-	newTaskDefinition, err := s.TaskDefinition.RegisterTaskDefinition(baseTaskDefinition, s.NewImage)
+	newTaskDefinition, err := s.TaskDefinition.RegisterTaskDefinitionWithContext(ctx, baseTaskDefinition, s.NewImage)
 	if err != nil {
-		return errors.Wrap(err, "Can not regist new task definition: ")
+		return nil, errors.Wrap(err, "Can not regist new task definition: ")
 	}
-	log.Infof("New task definition: %+v", newTaskDefinition)
-
-	err = s.UpdateService(service, newTaskDefinition)
+	s.logger().Infof("New task definition: %s", aws.StringValue(newTaskDefinition.TaskDefinitionArn))
+	result.TaskDefinitionArn = aws.StringValue(newTaskDefinition.TaskDefinitionArn)
+	s.emit(&Event{
+		Type:                      EventTaskDefinitionRegistered,
+		TaskDefinitionArn:         result.TaskDefinitionArn,
+		PreviousTaskDefinitionArn: result.PreviousTaskDefinitionArn,
+	})
+
+	updated, err := s.updateService(ctx, service, newTaskDefinition)
+	if updated != nil {
+		result.DeploymentID = primaryDeploymentID(updated)
+	}
+	result.Duration = time.Since(startedAt)
 	if err != nil {
-		log.Info("update failed")
+		s.logger().Info("update failed")
 		updateError := errors.Wrap(err, "Can not update service: ")
+		s.emit(&Event{
+			Type:                      EventDeployFailed,
+			TaskDefinitionArn:         result.TaskDefinitionArn,
+			PreviousTaskDefinitionArn: result.PreviousTaskDefinitionArn,
+			DeploymentID:              result.DeploymentID,
+			Err:                       updateError,
+		})
 		if !s.EnableRollback {
-			return updateError
+			return result, updateError
 		}
 
 		// rollback to the current task definition which have been running to the end
-		log.Infof("Rolling back to: %+v", currentTaskDefinition)
-		if err := s.Rollback(service, currentTaskDefinition); err != nil {
-			return errors.Wrap(updateError, err.Error())
+		s.logger().Infof("Rolling back to: %s", aws.StringValue(currentTaskDefinition.TaskDefinitionArn))
+		s.emit(&Event{
+			Type:                      EventRollbackStarted,
+			TaskDefinitionArn:         result.TaskDefinitionArn,
+			PreviousTaskDefinitionArn: result.PreviousTaskDefinitionArn,
+		})
+		// The deploy may fail because the context is canceled, so the rollback does not use the context.
+		rollbackCtx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+		defer cancel()
+		if err := s.RollbackWithContext(rollbackCtx, service, currentTaskDefinition); err != nil {
+			return result, errors.Wrap(updateError, err.Error())
 		}
-		return updateError
+		result.RolledBack = true
+		s.emit(&Event{
+			Type:                      EventRollbackCompleted,
+			TaskDefinitionArn:         result.TaskDefinitionArn,
+			PreviousTaskDefinitionArn: result.PreviousTaskDefinitionArn,
+		})
+		return result, updateError
 	}
-	return nil
+	return result, nil
 }
 
 // divideImageAndTag separates imageWithTag into repository and tag.
@@ -173,54 +331,346 @@ func divideImageAndTag(imageWithTag string) (*string, *string, error) {
 
 //Run run task on ECS based on provided task definition.
 func (t *Task) Run() ([]*ecs.Task, error) {
+	return t.RunWithContext(context.Background())
+}
+
+// RunWithContext is the same as Run with the context.
+// If the context is canceled, it stops waiting the tasks, but the running tasks are not stopped.
+func (t *Task) RunWithContext(ctx context.Context) ([]*ecs.Task, error) {
+	var tasks []*ecs.Task
+	err := t.withNotifications(func() ([]string, error) {
+		var err error
+		tasks, err = t.run(ctx)
+		return eventTaskArns(tasks), err
+	})
+	return tasks, err
+}
+
+// run runs the task, and waits for the task to stop.
+func (t *Task) run(ctx context.Context) ([]*ecs.Task, error) {
+	baseTaskDefinition, err := t.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.RunTaskWithContext(ctx, baseTaskDefinition)
+}
+
+// Start runs task on ECS based on provided task definition, and returns the started tasks without waiting.
+func (t *Task) Start() ([]*ecs.Task, error) {
+	return t.StartWithContext(context.Background())
+}
+
+// StartWithContext is the same as Start with the context.
+// The result of the tasks is unknown, so notifiers are notified only that the tasks are started, or that starting the tasks fails.
+func (t *Task) StartWithContext(ctx context.Context) ([]*ecs.Task, error) {
+	tasks, err := t.start(ctx)
+	n := &Notification{
+		Status:   StatusStarted,
+		TaskArns: eventTaskArns(tasks),
+		Error:    errorString(err),
+	}
+	if err != nil {
+		n.Status = StatusFailed
+	}
+	t.sendNotification(n)
+	return tasks, err
+}
+
+func (t *Task) start(ctx context.Context) ([]*ecs.Task, error) {
+	baseTaskDefinition, err := t.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.StartTaskWithContext(ctx, baseTaskDefinition)
+}
+
+// RunShards runs the task as a batch of shards based on provided task definition.
+// Please read RunBatch for more information.
+func (t *Task) RunShards(shards, concurrency int) ([]*TaskResult, error) {
+	return t.RunShardsWithContext(context.Background(), shards, concurrency)
+}
+
+// RunShardsWithContext is the same as RunShards with the context.
+func (t *Task) RunShardsWithContext(ctx context.Context, shards, concurrency int) ([]*TaskResult, error) {
+	var results []*TaskResult
+	err := t.withNotifications(func() ([]string, error) {
+		baseTaskDefinition, err := t.prepare(ctx)
+		if err != nil {
+			return nil, err
+		}
+		results, err = t.RunBatchWithContext(ctx, baseTaskDefinition, shards, concurrency)
+		arns := []string{}
+		for _, r := range results {
+			if r.TaskArn != "" {
+				arns = append(arns, r.TaskArn)
+			}
+		}
+		return arns, err
+	})
+	return results, err
+}
+
+// withNotifications notifies that the task is started, runs f, and notifies the result with ARNs of the tasks which f returns.
+func (t *Task) withNotifications(f func() ([]string, error)) error {
+	t.sendNotification(&Notification{Status: StatusStarted})
+	taskArns, err := f()
+	n := &Notification{
+		Status:   StatusSucceeded,
+		TaskArns: taskArns,
+		Error:    errorString(err),
+	}
+	if err != nil {
+		n.Status = StatusFailed
+	}
+	t.sendNotification(n)
+	return err
+}
+
+// prepare gets the task definition and network configuration to run the task.
+func (t *Task) prepare(ctx context.Context) (*ecs.TaskDefinition, error) {
 	if t.BaseTaskDefinition == "" {
 		return nil, errors.New("task definition is required")
 	}
 	// get a task definition
-	baseTaskDefinition, err := t.TaskDefinition.DescribeTaskDefinition(t.BaseTaskDefinition)
+	baseTaskDefinition, err := t.TaskDefinition.DescribeTaskDefinitionWithContext(ctx, t.BaseTaskDefinition)
 	if err != nil {
 		return nil, err
 	}
-
-	return t.RunTask(baseTaskDefinition)
+	if t.NetworkFromService != "" {
+		cluster, service := t.networkFromService()
+		if err := t.InheritNetworkConfigurationWithContext(ctx, cluster, service); err != nil {
+			return nil, errors.Wrap(err, "Can not get network configuration of the service: ")
+		}
+	}
+	return baseTaskDefinition, nil
 }
 
 // Create creates a new revision of the task definition.
 func (n *TaskDefinition) Create(base *string, dockerImage string) (*ecs.TaskDefinition, error) {
-	repository, revision, err := divideImageAndTag(dockerImage)
+	return n.CreateWithContext(context.Background(), base, dockerImage)
+}
+
+// TaskDefinitionResult is the result of CreateWithResult.
+type TaskDefinitionResult struct {
+	// Task definition which is created.
+	TaskDefinitionArn string
+
+	// Base task definition of the new revision.
+	PreviousTaskDefinitionArn string
+}
+
+// CreateWithContext is the same as Create with the context.
+func (n *TaskDefinition) CreateWithContext(ctx context.Context, base *string, dockerImage string) (*ecs.TaskDefinition, error) {
+	newTaskDefinition, _, err := n.create(ctx, base, dockerImage)
+	return newTaskDefinition, err
+}
+
+// CreateWithResult creates a new revision as same as Create, and returns ARNs of the new revision and the base task definition.
+func (n *TaskDefinition) CreateWithResult(base *string, dockerImage string) (*TaskDefinitionResult, error) {
+	newTaskDefinition, baseTaskDefinition, err := n.create(context.Background(), base, dockerImage)
 	if err != nil {
 		return nil, err
 	}
+	return &TaskDefinitionResult{
+		TaskDefinitionArn:         aws.StringValue(newTaskDefinition.TaskDefinitionArn),
+		PreviousTaskDefinitionArn: aws.StringValue(baseTaskDefinition.TaskDefinitionArn),
+	}, nil
+}
+
+// create registers a new revision, and returns the new revision and the base task definition.
+func (n *TaskDefinition) create(ctx context.Context, base *string, dockerImage string) (*ecs.TaskDefinition, *ecs.TaskDefinition, error) {
+	repository, revision, err := divideImageAndTag(dockerImage)
+	if err != nil {
+		return nil, nil, err
+	}
 	image := &Image{
 		Repository: *repository,
 		Tag:        *revision,
 	}
 	if base == nil {
+		return nil, nil, errors.New("task definition is required")
+	}
+	baseTaskDefinition, err := n.DescribeTaskDefinitionWithContext(ctx, *base)
+	if err != nil {
+		return nil, nil, err
+	}
+	newTaskDefinition, err := n.RegisterTaskDefinitionWithContext(ctx, baseTaskDefinition, image)
+	if err != nil {
+		return nil, nil, err
+	}
+	n.logger().Infof("New task definition: %s", aws.StringValue(newTaskDefinition.TaskDefinitionArn))
+
+	return newTaskDefinition, baseTaskDefinition, nil
+}
+
+// Update update the cloudwatch event or the schedule of EventBridge Scheduler with provided task definition.
+// If count is 0, the current count of the task is kept.
+func (s *ScheduledTask) Update(name string, taskDefinition *string, count int64) error {
+	_, err := s.UpdateWithContext(context.Background(), name, taskDefinition, count)
+	return err
+}
+
+// UpdateWithResult updates the scheduled task as same as Update, and returns the result of the update.
+func (s *ScheduledTask) UpdateWithResult(name string, taskDefinition *string, count int64) (*ScheduledTaskResult, error) {
+	return s.UpdateWithContext(context.Background(), name, taskDefinition, count)
+}
+
+// UpdateWithContext updates the scheduled task with the context, and returns the result of the update.
+func (s *ScheduledTask) UpdateWithContext(ctx context.Context, name string, taskDefinition *string, count int64) (*ScheduledTaskResult, error) {
+	s.sendNotification(&Notification{Status: StatusStarted, Rule: name, TaskDefinitionArn: aws.StringValue(taskDefinition)})
+	result, err := s.updateScheduledTask(ctx, name, taskDefinition, count)
+	n := &Notification{
+		Status:            StatusSucceeded,
+		Rule:              name,
+		TaskDefinitionArn: aws.StringValue(taskDefinition),
+		Error:             errorString(err),
+	}
+	if result != nil {
+		n.TaskDefinitionArn = result.TaskDefinitionArn
+		n.PreviousTaskDefinitionArn = result.PreviousTaskDefinitionArn
+	}
+	if err != nil {
+		n.Status = StatusFailed
+	}
+	s.sendNotification(n)
+	return result, err
+}
+
+// updateScheduledTask updates the rule or the schedule, and returns the result.
+func (s *ScheduledTask) updateScheduledTask(ctx context.Context, name string, taskDefinition *string, count int64) (*ScheduledTaskResult, error) {
+	if taskDefinition == nil {
 		return nil, errors.New("task definition is required")
 	}
-	baseTaskDefinition, err := n.DescribeTaskDefinition(*base)
+	// get a task definition
+	t, err := s.TaskDefinition.DescribeTaskDefinitionWithContext(ctx, *taskDefinition)
 	if err != nil {
 		return nil, err
 	}
-	newTaskDefinition, err := n.RegisterTaskDefinition(baseTaskDefinition, image)
+
+	backend, err := s.DetectBackendWithContext(ctx, name)
 	if err != nil {
 		return nil, err
 	}
-	log.Infof("New task definition: %+v", newTaskDefinition)
+	if backend == BackendScheduler {
+		return s.updateSchedule(ctx, count, t, name)
+	}
+	return s.updateTargets(ctx, count, t, name)
+}
 
-	return newTaskDefinition, nil
+// UpdateAll updates all scheduled tasks whose ECS targets run the family with provided task definition.
+// If family is empty, the family of the task definition is used.
+// Please read UpdateFamily for more information.
+func (s *ScheduledTask) UpdateAll(family string, taskDefinition *string, count int64, namePrefix string) ([]*ScheduledTaskResult, error) {
+	return s.UpdateAllWithContext(context.Background(), family, taskDefinition, count, namePrefix)
 }
 
-// Update update the cloudwatch event with provided task definition.
-func (s *ScheduledTask) Update(name string, taskDefinition *string, count int64) error {
+// UpdateAllWithContext is the same as UpdateAll with the context.
+func (s *ScheduledTask) UpdateAllWithContext(ctx context.Context, family string, taskDefinition *string, count int64, namePrefix string) ([]*ScheduledTaskResult, error) {
 	if taskDefinition == nil {
+		return nil, errors.New("task definition is required")
+	}
+	// get a task definition
+	t, err := s.TaskDefinition.DescribeTaskDefinitionWithContext(ctx, *taskDefinition)
+	if err != nil {
+		return nil, err
+	}
+	if family == "" {
+		family = aws.StringValue(t.Family)
+	}
+
+	return s.UpdateFamilyWithContext(ctx, count, t, family, namePrefix)
+}
+
+// Create creates or updates the scheduled task with the rule and ECS target.
+// It can be called repeatedly with the same rule.
+func (s *ScheduledTask) Create(rule *ScheduledTaskRule) error {
+	return s.CreateWithContext(context.Background(), rule)
+}
+
+// CreateWithContext is the same as Create with the context.
+func (s *ScheduledTask) CreateWithContext(ctx context.Context, rule *ScheduledTaskRule) error {
+	if rule.Name == "" {
+		return errors.New("name is required")
+	}
+	if rule.TaskDefinition == "" {
 		return errors.New("task definition is required")
 	}
+	if rule.Cluster == "" {
+		return errors.New("cluster is required")
+	}
 	// get a task definition
-	t, err := s.TaskDefinition.DescribeTaskDefinition(*taskDefinition)
+	t, err := s.TaskDefinition.DescribeTaskDefinitionWithContext(ctx, rule.TaskDefinition)
 	if err != nil {
 		return err
 	}
+	clusterArn, err := s.clusterArn(ctx, rule.Cluster)
+	if err != nil {
+		return errors.Wrap(err, "Can not get the cluster: ")
+	}
+	ruleArn, err := s.PutRuleWithContext(ctx, rule)
+	if err != nil {
+		return errors.Wrap(err, "Can not put the rule: ")
+	}
+	s.logger().Infof("Rule: %s", aws.StringValue(ruleArn))
+
+	return s.PutTargetWithContext(ctx, rule, clusterArn, t)
+}
+
+// Delete deletes the scheduled task with all targets of the rule.
+func (s *ScheduledTask) Delete(name string) error {
+	return s.DeleteWithContext(context.Background(), name)
+}
 
-	return s.UpdateTargets(count, t, name)
+// DeleteWithContext is the same as Delete with the context.
+func (s *ScheduledTask) DeleteWithContext(ctx context.Context, name string) error {
+	if name == "" {
+		return errors.New("name is required")
+	}
+	return s.DeleteRuleWithContext(ctx, name)
+}
+
+// Trigger runs the ECS targets of the rule immediately, and waits the tasks.
+// The task runs with the same overrides and network configuration as the targets.
+// If TargetIDs is set, only the targets are run.
+func (s *ScheduledTask) Trigger(name string, timeout time.Duration) ([]*ecs.Task, error) {
+	return s.TriggerWithContext(context.Background(), name, timeout)
+}
+
+// TriggerWithContext is the same as Trigger with the context.
+func (s *ScheduledTask) TriggerWithContext(ctx context.Context, name string, timeout time.Duration) ([]*ecs.Task, error) {
+	rule, err := s.DescribeRuleWithContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := s.ListsEventTargetsWithContext(ctx, rule.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []*ecs.Task{}
+	triggered := 0
+	for _, target := range targets {
+		if target.EcsParameters == nil {
+			continue
+		}
+		if len(s.TargetIDs) > 0 && !containsString(s.TargetIDs, aws.StringValue(target.Id)) {
+			continue
+		}
+		t, err := s.NewTaskFromTarget(name, target, timeout)
+		if err != nil {
+			return tasks, err
+		}
+		s.logger().Infof("Trigger the target: %s", aws.StringValue(target.Id))
+		result, err := t.RunWithContext(ctx)
+		tasks = append(tasks, result...)
+		if err != nil {
+			return tasks, err
+		}
+		triggered++
+	}
+	if triggered == 0 {
+		return nil, errors.Errorf("ECS target of %s is not found", name)
+	}
+	return tasks, nil
 }
@@ -1,7 +1,15 @@
 package deploy
 
 import (
+	"context"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
 )
 
 func TestDivideImageAndTag(t *testing.T) {
@@ -17,3 +25,127 @@ func TestDivideImageAndTag(t *testing.T) {
 		t.Errorf("tag is invalid: %s", *tag)
 	}
 }
+
+type mockedDeploy struct {
+	ecsiface.ECSAPI
+}
+
+func (m mockedDeploy) DescribeServicesWithContext(ctx aws.Context, in *ecs.DescribeServicesInput, opts ...request.Option) (*ecs.DescribeServicesOutput, error) {
+	return &ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{
+			&ecs.Service{
+				ServiceName:        aws.String("dummy-service"),
+				SchedulingStrategy: aws.String("REPLICA"),
+				TaskDefinition:     aws.String("dummy:1"),
+				Deployments: []*ecs.Deployment{
+					&ecs.Deployment{
+						Id:             aws.String("ecs-svc/2"),
+						TaskDefinition: aws.String("dummy:2"),
+						Status:         aws.String("PRIMARY"),
+						DesiredCount:   aws.Int64(1),
+						RunningCount:   aws.Int64(1),
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func (m mockedDeploy) DescribeTaskDefinitionWithContext(ctx aws.Context, in *ecs.DescribeTaskDefinitionInput, opts ...request.Option) (*ecs.DescribeTaskDefinitionOutput, error) {
+	return &ecs.DescribeTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{
+			TaskDefinitionArn: in.TaskDefinition,
+			Family:            aws.String("dummy"),
+		},
+	}, nil
+}
+
+func (m mockedDeploy) RegisterTaskDefinitionWithContext(ctx aws.Context, in *ecs.RegisterTaskDefinitionInput, opts ...request.Option) (*ecs.RegisterTaskDefinitionOutput, error) {
+	return &ecs.RegisterTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{
+			TaskDefinitionArn: aws.String("dummy:2"),
+			Family:            in.Family,
+		},
+	}, nil
+}
+
+func (m mockedDeploy) UpdateServiceWithContext(ctx aws.Context, in *ecs.UpdateServiceInput, opts ...request.Option) (*ecs.UpdateServiceOutput, error) {
+	return &ecs.UpdateServiceOutput{
+		Service: &ecs.Service{
+			ServiceName:  aws.String("dummy-service"),
+			DesiredCount: aws.Int64(1),
+			Deployments: []*ecs.Deployment{
+				&ecs.Deployment{Id: aws.String("ecs-svc/1"), Status: aws.String("ACTIVE")},
+				&ecs.Deployment{Id: aws.String("ecs-svc/2"), Status: aws.String("PRIMARY")},
+			},
+		},
+	}, nil
+}
+
+func TestDeployWithResult(t *testing.T) {
+	service := &Service{
+		awsECS:         mockedDeploy{},
+		Cluster:        "dummy-cluster",
+		Name:           "dummy-service",
+		TaskDefinition: &TaskDefinition{awsECS: mockedDeploy{}},
+		Timeout:        10 * time.Second,
+	}
+	result, err := service.DeployWithResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TaskDefinitionArn != "dummy:2" || result.PreviousTaskDefinitionArn != "dummy:1" {
+		t.Errorf("task definitions are invalid: %+v", result)
+	}
+	if result.DeploymentID != "ecs-svc/2" || result.RolledBack {
+		t.Errorf("deployment is invalid: %+v", result)
+	}
+}
+
+type mockedCanceledDeploy struct {
+	mockedDeploy
+	mu      sync.Mutex
+	updates []*ecs.UpdateServiceInput
+	errs    []error
+}
+
+func (m *mockedCanceledDeploy) UpdateServiceWithContext(ctx aws.Context, in *ecs.UpdateServiceInput, opts ...request.Option) (*ecs.UpdateServiceOutput, error) {
+	m.mu.Lock()
+	m.updates = append(m.updates, in)
+	m.errs = append(m.errs, ctx.Err())
+	m.mu.Unlock()
+	return m.mockedDeploy.UpdateServiceWithContext(ctx, in, opts...)
+}
+
+func TestDeployWithContextCanceledRollsBack(t *testing.T) {
+	client := &mockedCanceledDeploy{}
+	service := &Service{
+		awsECS:         client,
+		Cluster:        "dummy-cluster",
+		Name:           "dummy-service",
+		TaskDefinition: &TaskDefinition{awsECS: mockedDeploy{}},
+		Timeout:        10 * time.Second,
+		EnableRollback: true,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	result, err := service.DeployWithContext(ctx)
+	if err == nil {
+		t.Fatal("deploy should fail when the context is canceled")
+	}
+	if result == nil || !result.RolledBack {
+		t.Fatalf("service is not rolled back: %+v", result)
+	}
+	if len(client.updates) != 2 {
+		t.Fatalf("update-service should be called twice: %d", len(client.updates))
+	}
+	if *client.updates[1].TaskDefinition != "dummy:1" {
+		t.Errorf("rollback task definition is invalid: %s", *client.updates[1].TaskDefinition)
+	}
+	if client.errs[1] != nil {
+		t.Errorf("rollback should not use the canceled context: %v", client.errs[1])
+	}
+}
@@ -0,0 +1,78 @@
+package deploy
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// EventType is a type of the event which is sent to observers while deploying.
+type EventType string
+
+const (
+	// EventTaskDefinitionRegistered is sent when a new task definition is registered to deploy the service.
+	EventTaskDefinitionRegistered EventType = "TaskDefinitionRegistered"
+	// EventServiceUpdated is sent when update-service API succeeds. DeploymentID is set.
+	EventServiceUpdated EventType = "ServiceUpdated"
+	// EventNewTaskRunning is sent when the new task definition is deployed in the service.
+	EventNewTaskRunning EventType = "NewTaskRunning"
+	// EventDeployFailed is sent when the deploy of the service fails. Err is set.
+	EventDeployFailed EventType = "DeployFailed"
+	// EventRollbackStarted is sent when the service starts to roll back to PreviousTaskDefinitionArn.
+	EventRollbackStarted EventType = "RollbackStarted"
+	// EventRollbackCompleted is sent when the service is rolled back.
+	EventRollbackCompleted EventType = "RollbackCompleted"
+	// EventTasksStarted is sent when run-task API succeeds. TaskArns is set.
+	EventTasksStarted EventType = "TasksStarted"
+	// EventTasksStopped is sent when all of the tasks stop. Err is set if some tasks fail.
+	EventTasksStopped EventType = "TasksStopped"
+	// EventScheduledTaskUpdated is sent when the targets of the rule or the schedule are updated.
+	EventScheduledTaskUpdated EventType = "ScheduledTaskUpdated"
+)
+
+// Event is sent to observers while deploying. Fields which are not related to the event type are empty.
+type Event struct {
+	Type EventType
+
+	// Name of ECS cluster.
+	Cluster string
+	// Name of ECS service.
+	Service string
+	// Name of the rule or the schedule of the scheduled task.
+	Rule string
+
+	TaskDefinitionArn         string
+	PreviousTaskDefinitionArn string
+	DeploymentID              string
+	TaskArns                  []string
+	Err                       error
+}
+
+// Observer receives events of Service, Task and ScheduledTask.
+// OnEvent is called synchronously, and it may be called from multiple goroutines when the task runs as shards.
+type Observer interface {
+	OnEvent(event *Event)
+}
+
+// ObserverFunc is an adapter to use a function as Observer.
+type ObserverFunc func(event *Event)
+
+// OnEvent calls f(event).
+func (f ObserverFunc) OnEvent(event *Event) {
+	f(event)
+}
+
+// notifyObservers sends the event to all observers.
+func notifyObservers(observers []Observer, event *Event) {
+	for _, o := range observers {
+		o.OnEvent(event)
+	}
+}
+
+// eventTaskArns returns ARNs of the tasks.
+func eventTaskArns(tasks []*ecs.Task) []string {
+	arns := []string{}
+	for _, task := range tasks {
+		arns = append(arns, aws.StringValue(task.TaskArn))
+	}
+	return arns
+}
@@ -0,0 +1,43 @@
+package deploy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeployEvents(t *testing.T) {
+	events := []*Event{}
+	service := &Service{
+		awsECS:         mockedDeploy{},
+		Cluster:        "dummy-cluster",
+		Name:           "dummy-service",
+		TaskDefinition: &TaskDefinition{awsECS: mockedDeploy{}},
+		Timeout:        10 * time.Second,
+		Observers: []Observer{
+			ObserverFunc(func(event *Event) {
+				events = append(events, event)
+			}),
+		},
+	}
+	if err := service.Deploy(); err != nil {
+		t.Fatal(err)
+	}
+	types := []EventType{EventTaskDefinitionRegistered, EventServiceUpdated, EventNewTaskRunning}
+	if len(events) != len(types) {
+		t.Fatalf("events are invalid: %+v", events)
+	}
+	for i, event := range events {
+		if event.Type != types[i] {
+			t.Errorf("type of the event is invalid: %s", event.Type)
+		}
+		if event.Cluster != "dummy-cluster" || event.Service != "dummy-service" {
+			t.Errorf("event is invalid: %+v", event)
+		}
+	}
+	if events[0].TaskDefinitionArn != "dummy:2" || events[0].PreviousTaskDefinitionArn != "dummy:1" {
+		t.Errorf("task definitions are invalid: %+v", events[0])
+	}
+	if events[1].DeploymentID != "ecs-svc/2" {
+		t.Errorf("deployment is invalid: %+v", events[1])
+	}
+}
@@ -0,0 +1,275 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// sessionManagerPlugin is the command to connect to the session of execute-command.
+// Please install it: https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html
+const sessionManagerPlugin = "session-manager-plugin"
+
+// Exec has target ECS information and client of aws-sdk-go to execute a command in a running task.
+type Exec struct {
+	awsECS ecsiface.ECSAPI
+
+	// Name of ECS cluster.
+	Cluster string
+
+	// Name of ECS service. A running task of the service is selected, if Task is empty.
+	Service string
+
+	// ARN or ID of the task. If this is set, Service is ignored.
+	Task string
+
+	// Name of the container. It can be empty if the task has only one container.
+	Container string
+
+	// Command which is executed in the container.
+	Command string
+
+	// Logger of the execution.
+	Logger *log.Entry
+
+	profile string
+	region  string
+	// defaultLogger is used when Logger is nil.
+	defaultLogger *log.Entry
+	verbose       bool
+}
+
+// NewExec returns a new Exec struct, and initialize aws ecs API client.
+func NewExec(cluster, service, task, container, command, profile, region string, verbose bool) *Exec {
+	e := NewExecWithOptions(cluster, service, WithCommand(container, command), WithProfile(profile), WithRegion(region), WithVerbose(verbose))
+	e.Task = task
+	return e
+}
+
+// NewExecWithOptions returns a new Exec struct configured with the options.
+// The container and the command are set with WithCommand.
+func NewExecWithOptions(cluster, service string, opts ...Option) *Exec {
+	o := newOptions(opts)
+	return &Exec{
+		awsECS:    o.ecs(),
+		Cluster:   cluster,
+		Service:   service,
+		Container: o.container,
+		Command:   o.command,
+		Logger:    o.newLogger().WithField("cluster", cluster),
+		profile:   o.profile,
+		region:    o.regionName(),
+		verbose:   o.verbose,
+	}
+}
+
+func (e *Exec) logger() *log.Entry {
+	if e.Logger != nil {
+		return e.Logger
+	}
+	if e.defaultLogger == nil {
+		e.defaultLogger = newLogger(e.verbose)
+	}
+	return e.defaultLogger
+}
+
+// Run executes the command in the container, and connects stdin, stdout and stderr to the session.
+// If stdin is a terminal, the session is interactive.
+func (e *Exec) Run(stdin io.Reader, stdout, stderr io.Writer) error {
+	return e.RunWithContext(context.Background(), stdin, stdout, stderr)
+}
+
+// RunWithContext is the same as Run with the context.
+func (e *Exec) RunWithContext(ctx context.Context, stdin io.Reader, stdout, stderr io.Writer) error {
+	task, err := e.SelectTaskWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	container, err := e.selectContainer(task)
+	if err != nil {
+		return err
+	}
+	output, err := e.ExecuteCommandWithContext(ctx, task, container)
+	if err != nil {
+		return err
+	}
+	return e.startSession(ctx, output, task, container, stdin, stdout, stderr)
+}
+
+// Output executes the command in the container without stdin, and returns the captured output of the command.
+func (e *Exec) Output() (string, error) {
+	return e.OutputWithContext(context.Background())
+}
+
+// OutputWithContext is the same as Output with the context.
+func (e *Exec) OutputWithContext(ctx context.Context) (string, error) {
+	var stdout bytes.Buffer
+	if err := e.RunWithContext(ctx, nil, &stdout, os.Stderr); err != nil {
+		return stdout.String(), err
+	}
+	return stdout.String(), nil
+}
+
+// SelectTask returns the task to execute the command.
+// If Task is not set, it selects a running task of the service which enables execute-command.
+func (e *Exec) SelectTask() (*ecs.Task, error) {
+	return e.SelectTaskWithContext(context.Background())
+}
+
+// SelectTaskWithContext is the same as SelectTask with the context.
+func (e *Exec) SelectTaskWithContext(ctx context.Context) (*ecs.Task, error) {
+	var taskArns []*string
+	if e.Task != "" {
+		taskArns = []*string{aws.String(e.Task)}
+	} else {
+		if e.Service == "" {
+			return nil, errors.New("service or task is required")
+		}
+		input := &ecs.ListTasksInput{
+			Cluster:       aws.String(e.Cluster),
+			ServiceName:   aws.String(e.Service),
+			DesiredStatus: aws.String("RUNNING"),
+		}
+		resp, err := e.awsECS.ListTasksWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		taskArns = resp.TaskArns
+	}
+	if len(taskArns) == 0 {
+		return nil, errors.Errorf("running task of %s is not found", e.Service)
+	}
+
+	params := &ecs.DescribeTasksInput{
+		Cluster: aws.String(e.Cluster),
+		Tasks:   taskArns,
+	}
+	resp, err := e.awsECS.DescribeTasksWithContext(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range resp.Tasks {
+		if aws.StringValue(task.LastStatus) != "RUNNING" {
+			continue
+		}
+		if !aws.BoolValue(task.EnableExecuteCommand) {
+			e.logger().Infof("Execute command is not enabled in %s", aws.StringValue(task.TaskArn))
+			continue
+		}
+		return task, nil
+	}
+	if e.Task != "" {
+		return nil, errors.Errorf("task %s is not running or does not enable execute command", e.Task)
+	}
+	return nil, errors.Errorf("running task of %s which enables execute command is not found", e.Service)
+}
+
+// ExecuteCommand calls execute-command API, and returns a session to connect.
+func (e *Exec) ExecuteCommand(task *ecs.Task, container *ecs.Container) (*ecs.ExecuteCommandOutput, error) {
+	return e.ExecuteCommandWithContext(context.Background(), task, container)
+}
+
+// ExecuteCommandWithContext is the same as ExecuteCommand with the context.
+func (e *Exec) ExecuteCommandWithContext(ctx context.Context, task *ecs.Task, container *ecs.Container) (*ecs.ExecuteCommandOutput, error) {
+	params := &ecs.ExecuteCommandInput{
+		Cluster:   aws.String(e.Cluster),
+		Task:      task.TaskArn,
+		Container: container.Name,
+		Command:   aws.String(e.Command),
+		// Execute command supports only interactive mode.
+		Interactive: aws.Bool(true),
+	}
+	resp, err := e.awsECS.ExecuteCommandWithContext(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	e.logger().Infof("Session: %s", aws.StringValue(resp.Session.SessionId))
+	return resp, nil
+}
+
+// selectContainer finds the target container in the task.
+func (e *Exec) selectContainer(task *ecs.Task) (*ecs.Container, error) {
+	if e.Container == "" {
+		if len(task.Containers) != 1 {
+			return nil, errors.New("container name is required, because the task has multiple containers")
+		}
+		return task.Containers[0], nil
+	}
+	for _, c := range task.Containers {
+		if aws.StringValue(c.Name) == e.Container {
+			return c, nil
+		}
+	}
+	return nil, errors.Errorf("container %s is not found in %s", e.Container, aws.StringValue(task.TaskArn))
+}
+
+// startSession connects to the session with session-manager-plugin as same as AWS CLI.
+func (e *Exec) startSession(ctx context.Context, output *ecs.ExecuteCommandOutput, task *ecs.Task, container *ecs.Container, stdin io.Reader, stdout, stderr io.Writer) error {
+	if _, err := exec.LookPath(sessionManagerPlugin); err != nil {
+		return errors.Wrap(err, "session-manager-plugin is required to execute command")
+	}
+	sessionJSON, err := json.Marshal(output.Session)
+	if err != nil {
+		return err
+	}
+	targetJSON, err := json.Marshal(map[string]string{
+		"Target": sessionTarget(task, container),
+	})
+	if err != nil {
+		return err
+	}
+	endpoint, err := ssmEndpoint(e.region)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, sessionManagerPlugin, string(sessionJSON), e.region, "StartSession", e.profile, string(targetJSON), endpoint)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// Interrupt should be sent to the command in the container, not to this process.
+	// It is received with a private channel, so handlers of the program are not changed.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-interrupts:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return cmd.Run()
+}
+
+// ssmEndpoint returns the endpoint of SSM in the region, which depends on the partition of the region.
+func ssmEndpoint(region string) (string, error) {
+	endpoint, err := endpoints.DefaultResolver().EndpointFor("ssm", region)
+	if err != nil {
+		return "", err
+	}
+	return endpoint.URL, nil
+}
+
+// sessionTarget returns the target of SSM session for the container.
+func sessionTarget(task *ecs.Task, container *ecs.Container) string {
+	clusterArn := strings.Split(aws.StringValue(task.ClusterArn), "/")
+	taskArn := strings.Split(aws.StringValue(task.TaskArn), "/")
+	return "ecs:" + clusterArn[len(clusterArn)-1] + "_" + taskArn[len(taskArn)-1] + "_" + aws.StringValue(container.RuntimeId)
+}
@@ -0,0 +1,108 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+type mockedSelectTask struct {
+	ecsiface.ECSAPI
+	List     ecs.ListTasksOutput
+	Describe ecs.DescribeTasksOutput
+}
+
+func (m mockedSelectTask) ListTasksWithContext(ctx aws.Context, in *ecs.ListTasksInput, opts ...request.Option) (*ecs.ListTasksOutput, error) {
+	return &m.List, nil
+}
+
+func (m mockedSelectTask) DescribeTasksWithContext(ctx aws.Context, in *ecs.DescribeTasksInput, opts ...request.Option) (*ecs.DescribeTasksOutput, error) {
+	return &m.Describe, nil
+}
+
+func TestSelectTask(t *testing.T) {
+	list := ecs.ListTasksOutput{
+		TaskArns: []*string{
+			aws.String("disabled-task-arn"),
+			aws.String("enabled-task-arn"),
+		},
+	}
+	describe := ecs.DescribeTasksOutput{
+		Tasks: []*ecs.Task{
+			&ecs.Task{
+				TaskArn:              aws.String("disabled-task-arn"),
+				LastStatus:           aws.String("RUNNING"),
+				EnableExecuteCommand: aws.Bool(false),
+			},
+			&ecs.Task{
+				TaskArn:              aws.String("enabled-task-arn"),
+				LastStatus:           aws.String("RUNNING"),
+				EnableExecuteCommand: aws.Bool(true),
+			},
+		},
+	}
+	e := NewExecWithOptions("dummy-cluster", "dummy-service", WithECSClient(mockedSelectTask{List: list, Describe: describe}))
+	task, err := e.SelectTask()
+	if err != nil {
+		t.Error(err)
+	}
+	if *task.TaskArn != "enabled-task-arn" {
+		t.Errorf("task is invalid: %s", *task.TaskArn)
+	}
+}
+
+func TestSelectTaskNotFound(t *testing.T) {
+	describe := ecs.DescribeTasksOutput{
+		Tasks: []*ecs.Task{
+			&ecs.Task{
+				TaskArn:    aws.String("stopped-task-arn"),
+				LastStatus: aws.String("STOPPED"),
+			},
+		},
+	}
+	e := NewExecWithOptions("dummy-cluster", "dummy-service", WithECSClient(mockedSelectTask{Describe: describe}))
+	e.Task = "stopped-task-arn"
+	_, err := e.SelectTask()
+	if err == nil || err.Error() != "task stopped-task-arn is not running or does not enable execute command" {
+		t.Errorf("error is invalid: %v", err)
+	}
+}
+
+func TestSessionTarget(t *testing.T) {
+	task := &ecs.Task{
+		ClusterArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:cluster/my-cluster"),
+		TaskArn:    aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/my-cluster/0123456789abcdef"),
+		Containers: []*ecs.Container{
+			&ecs.Container{
+				Name:      aws.String("web"),
+				RuntimeId: aws.String("0123456789abcdef-111111111"),
+			},
+		},
+	}
+	e := NewExecWithOptions("dummy-cluster", "", WithECSClient(mockedSelectTask{}), WithCommand("web", "/bin/sh"), WithRegion("ap-northeast-1"))
+	if e.Container != "web" || e.Command != "/bin/sh" || e.region != "ap-northeast-1" {
+		t.Errorf("exec is invalid: %+v", e)
+	}
+	container, err := e.selectContainer(task)
+	if err != nil {
+		t.Error(err)
+	}
+	target := sessionTarget(task, container)
+	if target != "ecs:my-cluster_0123456789abcdef_0123456789abcdef-111111111" {
+		t.Errorf("target is invalid: %s", target)
+	}
+}
+
+func TestSSMEndpoint(t *testing.T) {
+	endpoint, err := ssmEndpoint("ap-northeast-1")
+	if err != nil || endpoint != "https://ssm.ap-northeast-1.amazonaws.com" {
+		t.Errorf("endpoint is invalid: %s, %v", endpoint, err)
+	}
+	endpoint, err = ssmEndpoint("cn-north-1")
+	if err != nil || endpoint != "https://ssm.cn-north-1.amazonaws.com.cn" {
+		t.Errorf("endpoint is invalid: %s, %v", endpoint, err)
+	}
+}
@@ -0,0 +1,71 @@
+package deploy
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// Hook is a shell command which runs locally around the deploy.
+type Hook struct {
+	// Command which is run with sh -c.
+	Command string
+
+	// Stdout and Stderr of the command. If they are nil, os.Stderr is used, so the hook does not mix with the result of ecs-goploy.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// HookEnvironment describes the deploy for the hook. It is passed to the hook as environment variables.
+type HookEnvironment struct {
+	Cluster                   string
+	Service                   string
+	TaskDefinitionArn         string
+	PreviousTaskDefinitionArn string
+	Image                     string
+	DeploymentID              string
+	Status                    Status
+}
+
+// Environ returns environment variables (KEY=VALUE) of the deploy.
+func (e *HookEnvironment) Environ() []string {
+	return []string{
+		"ECS_GOPLOY_CLUSTER=" + e.Cluster,
+		"ECS_GOPLOY_SERVICE=" + e.Service,
+		"ECS_GOPLOY_TASK_DEFINITION_ARN=" + e.TaskDefinitionArn,
+		"ECS_GOPLOY_PREVIOUS_TASK_DEFINITION_ARN=" + e.PreviousTaskDefinitionArn,
+		"ECS_GOPLOY_IMAGE=" + e.Image,
+		"ECS_GOPLOY_DEPLOYMENT_ID=" + e.DeploymentID,
+		"ECS_GOPLOY_STATUS=" + string(e.Status),
+	}
+}
+
+// Run runs the command of the hook with environment variables of the deploy.
+// If Command is empty, it does nothing.
+func (h *Hook) Run(env *HookEnvironment) error {
+	return h.RunWithContext(context.Background(), env)
+}
+
+// RunWithContext is the same as Run with the context.
+func (h *Hook) RunWithContext(ctx context.Context, env *HookEnvironment) error {
+	if h.Command == "" {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.Env = append(os.Environ(), env.Environ()...)
+	cmd.Stdout = h.Stdout
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stderr
+	}
+	cmd.Stderr = h.Stderr
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "hook failed: %s", h.Command)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package deploy
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHookRun(t *testing.T) {
+	var stdout bytes.Buffer
+	hook := &Hook{
+		Command: `echo "$ECS_GOPLOY_STATUS $ECS_GOPLOY_SERVICE $ECS_GOPLOY_TASK_DEFINITION_ARN"`,
+		Stdout:  &stdout,
+	}
+	env := &HookEnvironment{
+		Cluster:           "dummy-cluster",
+		Service:           "dummy-service",
+		TaskDefinitionArn: "dummy:2",
+		Status:            StatusSucceeded,
+	}
+	if err := hook.Run(env); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "succeeded dummy-service dummy:2\n" {
+		t.Errorf("output of the hook is invalid: %s", stdout.String())
+	}
+
+	hook = &Hook{Command: "exit 1", Stdout: &stdout, Stderr: &stdout}
+	if err := hook.Run(env); err == nil {
+		t.Error("failing hook should be error")
+	}
+
+	hook = &Hook{}
+	if err := hook.Run(env); err != nil {
+		t.Errorf("empty hook should not be error: %v", err)
+	}
+}
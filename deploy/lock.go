@@ -0,0 +1,192 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/pkg/errors"
+)
+
+// ErrLocked is returned when another deploy of the service is in progress.
+var ErrLocked = errors.New("another deploy of the service is in progress")
+
+// lockInterval is the interval to retry the lock while waiting for it.
+var lockInterval = 5 * time.Second
+
+// Locker prevents concurrent deploys of the same service.
+type Locker interface {
+	// Lock acquires the lock of the service. It returns ErrLocked if another deploy holds the lock.
+	Lock(ctx context.Context, cluster, service string) error
+	// Unlock releases the lock of the service.
+	Unlock(ctx context.Context, cluster, service string) error
+}
+
+// DynamoDBLocker locks the service with an item of the DynamoDB table.
+// The table must have a string partition key named LockID.
+// Please enable TTL of the table with ExpiresAt, so the lock of the deploy which is killed expires.
+type DynamoDBLocker struct {
+	awsDynamoDB dynamodbiface.DynamoDBAPI
+
+	// Name of the DynamoDB table.
+	Table string
+
+	// Time to live of the lock. The expired lock can be acquired by another deploy, even if the item is not deleted yet.
+	// Please set it longer than Timeout of the service.
+	TTL time.Duration
+
+	// Owner of the lock. NewDynamoDBLocker sets the hostname and the process ID to this.
+	Owner string
+}
+
+// NewDynamoDBLocker returns a new DynamoDBLocker which uses the table, and initialize aws dynamodb API client.
+func NewDynamoDBLocker(table string, opts ...Option) *DynamoDBLocker {
+	o := newOptions(opts)
+	hostname, _ := os.Hostname()
+	return &DynamoDBLocker{
+		awsDynamoDB: o.dynamoDB(),
+		Table:       table,
+		TTL:         time.Hour,
+		Owner:       fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+	}
+}
+
+// Lock puts the lock item unless another owner holds the lock which has not expired.
+func (d *DynamoDBLocker) Lock(ctx context.Context, cluster, service string) error {
+	now := time.Now()
+	params := &dynamodb.PutItemInput{
+		TableName: aws.String(d.Table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"LockID":    {S: aws.String(lockID(cluster, service))},
+			"Owner":     {S: aws.String(d.Owner)},
+			"ExpiresAt": {N: aws.String(strconv.FormatInt(now.Add(d.TTL).Unix(), 10))},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(LockID) OR ExpiresAt < :now"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(strconv.FormatInt(now.Unix(), 10))},
+		},
+	}
+	_, err := d.awsDynamoDB.PutItemWithContext(ctx, params)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrLocked
+	}
+	return err
+}
+
+// Unlock deletes the lock item if the owner holds it.
+func (d *DynamoDBLocker) Unlock(ctx context.Context, cluster, service string) error {
+	params := &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.Table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"LockID": {S: aws.String(lockID(cluster, service))},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner": aws.String("Owner"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(d.Owner)},
+		},
+	}
+	_, err := d.awsDynamoDB.DeleteItemWithContext(ctx, params)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		// The lock has expired and another deploy holds it.
+		return nil
+	}
+	return err
+}
+
+func lockID(cluster, service string) string {
+	return cluster + "/" + service
+}
+
+// ECSDeploymentLocker refuses to deploy while the service has more than one active deployment.
+// It does not need any resources, but it can not prevent deploys which start at the same time.
+type ECSDeploymentLocker struct {
+	awsECS ecsiface.ECSAPI
+}
+
+// NewECSDeploymentLocker returns a new ECSDeploymentLocker, and initialize aws ecs API client.
+func NewECSDeploymentLocker(opts ...Option) *ECSDeploymentLocker {
+	o := newOptions(opts)
+	return &ECSDeploymentLocker{
+		awsECS: o.ecs(),
+	}
+}
+
+// Lock returns ErrLocked if the service has more than one deployment which is not INACTIVE.
+func (e *ECSDeploymentLocker) Lock(ctx context.Context, cluster, service string) error {
+	params := &ecs.DescribeServicesInput{
+		Cluster:  aws.String(cluster),
+		Services: []*string{aws.String(service)},
+	}
+	resp, err := e.awsECS.DescribeServicesWithContext(ctx, params)
+	if err != nil {
+		return err
+	}
+	if len(resp.Services) == 0 {
+		return errors.Errorf("service %s is not found", service)
+	}
+	active := 0
+	for _, d := range resp.Services[0].Deployments {
+		if aws.StringValue(d.Status) != "INACTIVE" {
+			active++
+		}
+	}
+	if active > 1 {
+		return ErrLocked
+	}
+	return nil
+}
+
+// Unlock does nothing, because the deployment of ECS is the lock.
+func (e *ECSDeploymentLocker) Unlock(ctx context.Context, cluster, service string) error {
+	return nil
+}
+
+// lock acquires the lock of the service with Locker.
+// If LockTimeout is set, it waits for the lock until the timeout, and returns ErrLocked when the timeout expires.
+// If the context is done while waiting, it returns the error of the context.
+func (s *Service) lock(ctx context.Context) error {
+	if s.Locker == nil {
+		return nil
+	}
+	waitCtx := ctx
+	if s.LockTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, s.LockTimeout)
+		defer cancel()
+	}
+	for {
+		err := s.Locker.Lock(waitCtx, s.Cluster, s.Name)
+		if err != ErrLocked || s.LockTimeout <= 0 {
+			return err
+		}
+		s.logger().Info("Waiting for the lock of the service...")
+		if err := sleep(waitCtx, lockInterval); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.logger().Errorf("Gave up waiting for the lock: %v", err)
+			return ErrLocked
+		}
+	}
+}
+
+// unlock releases the lock of the service. It is called even if the context is canceled.
+func (s *Service) unlock() {
+	if s.Locker == nil {
+		return
+	}
+	if err := s.Locker.Unlock(context.Background(), s.Cluster, s.Name); err != nil {
+		s.logger().Errorf("Failed to unlock the service: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package deploy
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+type mockedLockTable struct {
+	dynamodbiface.DynamoDBAPI
+	Items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func (m mockedLockTable) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	id := aws.StringValue(in.Item["LockID"].S)
+	if current, ok := m.Items[id]; ok {
+		expiresAt, _ := strconv.ParseInt(aws.StringValue(current["ExpiresAt"].N), 10, 64)
+		now, _ := strconv.ParseInt(aws.StringValue(in.ExpressionAttributeValues[":now"].N), 10, 64)
+		if expiresAt >= now {
+			return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+		}
+	}
+	m.Items[id] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m mockedLockTable) DeleteItemWithContext(ctx aws.Context, in *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	id := aws.StringValue(in.Key["LockID"].S)
+	current, ok := m.Items[id]
+	if !ok || aws.StringValue(current["Owner"].S) != aws.StringValue(in.ExpressionAttributeValues[":owner"].S) {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+	}
+	delete(m.Items, id)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestDynamoDBLocker(t *testing.T) {
+	table := mockedLockTable{Items: map[string]map[string]*dynamodb.AttributeValue{}}
+	first := NewDynamoDBLocker("locks", WithDynamoDBClient(table))
+	first.Owner = "first"
+	second := NewDynamoDBLocker("locks", WithDynamoDBClient(table))
+	second.Owner = "second"
+	ctx := context.Background()
+
+	if err := first.Lock(ctx, "dummy-cluster", "dummy-service"); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Lock(ctx, "dummy-cluster", "dummy-service"); err != ErrLocked {
+		t.Errorf("lock should be held by first: %v", err)
+	}
+	if err := second.Lock(ctx, "dummy-cluster", "other-service"); err != nil {
+		t.Errorf("other service should be locked: %v", err)
+	}
+	if err := second.Unlock(ctx, "dummy-cluster", "dummy-service"); err != nil {
+		t.Error(err)
+	}
+	if _, ok := table.Items["dummy-cluster/dummy-service"]; !ok {
+		t.Error("lock of first should not be deleted by second")
+	}
+	if err := first.Unlock(ctx, "dummy-cluster", "dummy-service"); err != nil {
+		t.Error(err)
+	}
+	if err := second.Lock(ctx, "dummy-cluster", "dummy-service"); err != nil {
+		t.Errorf("lock should be released: %v", err)
+	}
+
+	// The expired lock can be acquired.
+	second.TTL = -time.Minute
+	if err := second.Lock(ctx, "dummy-cluster", "expired-service"); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Lock(ctx, "dummy-cluster", "expired-service"); err != nil {
+		t.Errorf("expired lock should be acquired: %v", err)
+	}
+}
+
+func TestECSDeploymentLocker(t *testing.T) {
+	resp := ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{
+			{
+				ServiceName: aws.String("dummy-service"),
+				Deployments: []*ecs.Deployment{
+					{Id: aws.String("ecs-svc/2"), Status: aws.String("PRIMARY")},
+					{Id: aws.String("ecs-svc/1"), Status: aws.String("ACTIVE")},
+				},
+			},
+		},
+	}
+	locker := NewECSDeploymentLocker(WithECSClient(mockedDescribeServices{Resp: resp}))
+	if err := locker.Lock(context.Background(), "dummy-cluster", "dummy-service"); err != ErrLocked {
+		t.Errorf("service which is deploying should be locked: %v", err)
+	}
+
+	resp.Services[0].Deployments[1].Status = aws.String("INACTIVE")
+	locker = NewECSDeploymentLocker(WithECSClient(mockedDescribeServices{Resp: resp}))
+	if err := locker.Lock(context.Background(), "dummy-cluster", "dummy-service"); err != nil {
+		t.Errorf("inactive deployment should be ignored: %v", err)
+	}
+
+	resp.Services[0].Deployments = resp.Services[0].Deployments[:1]
+	locker = NewECSDeploymentLocker(WithECSClient(mockedDescribeServices{Resp: resp}))
+	if err := locker.Lock(context.Background(), "dummy-cluster", "dummy-service"); err != nil {
+		t.Error(err)
+	}
+}
+
+type countedLocker struct {
+	locked   int
+	attempts int
+}
+
+func (c *countedLocker) Lock(ctx context.Context, cluster, service string) error {
+	c.attempts++
+	if c.attempts <= c.locked {
+		return ErrLocked
+	}
+	return nil
+}
+
+func (c *countedLocker) Unlock(ctx context.Context, cluster, service string) error {
+	return nil
+}
+
+func TestDeployWaitsForLock(t *testing.T) {
+	interval := lockInterval
+	defer func() { lockInterval = interval }()
+	lockInterval = 10 * time.Millisecond
+
+	locker := &countedLocker{locked: 100}
+	service := &Service{Cluster: "dummy-cluster", Name: "dummy-service", Locker: locker}
+	if _, err := service.DeployWithResult(); err != ErrLocked || locker.attempts != 1 {
+		t.Errorf("deploy should fail immediately: %v, %d", err, locker.attempts)
+	}
+
+	locker = &countedLocker{locked: 2}
+	service = &Service{Cluster: "dummy-cluster", Name: "dummy-service", Locker: locker, LockTimeout: time.Second}
+	if err := service.lock(context.Background()); err != nil || locker.attempts != 3 {
+		t.Errorf("lock should be acquired after waiting: %v, %d", err, locker.attempts)
+	}
+
+	locker = &countedLocker{locked: 100}
+	service = &Service{Cluster: "dummy-cluster", Name: "dummy-service", Locker: locker, LockTimeout: 50 * time.Millisecond}
+	if err := service.lock(context.Background()); err != ErrLocked {
+		t.Errorf("lock should time out: %v", err)
+	}
+
+	// Canceling the deploy is not the timeout of the lock.
+	locker = &countedLocker{locked: 100}
+	service = &Service{Cluster: "dummy-cluster", Name: "dummy-service", Locker: locker, LockTimeout: time.Second}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if err := service.lock(ctx); err != context.Canceled {
+		t.Errorf("lock should be canceled: %v", err)
+	}
+}
+
+func TestDeployRunsPreDeployAfterLock(t *testing.T) {
+	resp := ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{
+			{ServiceName: aws.String("dummy-service"), TaskDefinition: aws.String("dummy-task-definition:1")},
+		},
+	}
+	abort := errors.New("abort")
+	var called []string
+	preDeploy := func(ctx context.Context, service *ecs.Service) error {
+		called = append(called, aws.StringValue(service.TaskDefinition))
+		return abort
+	}
+
+	service := &Service{awsECS: mockedDescribeServices{Resp: resp}, Cluster: "dummy-cluster", Name: "dummy-service", Locker: &countedLocker{locked: 1}, PreDeploy: preDeploy}
+	if _, err := service.DeployWithResult(); err != ErrLocked || len(called) != 0 {
+		t.Errorf("pre-deploy should not be called without the lock: %v, %v", err, called)
+	}
+
+	service = &Service{awsECS: mockedDescribeServices{Resp: resp}, Cluster: "dummy-cluster", Name: "dummy-service", Locker: &countedLocker{}, PreDeploy: preDeploy}
+	if _, err := service.DeployWithResult(); errors.Cause(err) != abort || len(called) != 1 || called[0] != "dummy-task-definition:1" {
+		t.Errorf("pre-deploy should abort the deploy: %v, %v", err, called)
+	}
+}
@@ -0,0 +1,23 @@
+package deploy
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// newLogger returns a logger which is independent of the global logger of logrus.
+// It outputs only errors unless verbose is true.
+func newLogger(verbose bool) *log.Entry {
+	logger := log.New()
+	if !verbose {
+		logger.SetLevel(log.ErrorLevel)
+	}
+	return log.NewEntry(logger)
+}
+
+// loggerOrDefault returns the logger, or a new logger if it is nil.
+func loggerOrDefault(logger *log.Entry, verbose bool) *log.Entry {
+	if logger != nil {
+		return logger
+	}
+	return newLogger(verbose)
+}
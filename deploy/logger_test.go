@@ -0,0 +1,55 @@
+package deploy
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestNewServiceKeepsGlobalLogger(t *testing.T) {
+	level := log.GetLevel()
+	defer log.SetLevel(level)
+	log.SetLevel(log.InfoLevel)
+
+	service, err := NewServiceWithOptions("dummy-cluster", "dummy-service", WithECSClient(mockedECS{}), WithVerbose(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log.GetLevel() != log.InfoLevel {
+		t.Errorf("global log level is changed: %s", log.GetLevel())
+	}
+	if service.Logger.Logger.GetLevel() != log.ErrorLevel {
+		t.Errorf("log level of the service is invalid: %s", service.Logger.Logger.GetLevel())
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	task, err := NewTaskWithOptions("dummy-cluster", "", WithECSClient(mockedECS{}), WithLogger(log.NewEntry(logger)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	task.logger().Info("dummy")
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("log is not written to the logger")
+	}
+	if entry.Data["cluster"] != "dummy-cluster" {
+		t.Errorf("fields of the log are invalid: %v", entry.Data)
+	}
+	if task.TaskDefinition.Logger == nil {
+		t.Error("logger is not shared with the task definition")
+	}
+
+}
+
+func TestDefaultLoggerIsCached(t *testing.T) {
+	service := &Service{}
+	if service.logger() != service.logger() {
+		t.Error("default logger should be created once")
+	}
+	if service.logger().Logger == log.StandardLogger() {
+		t.Error("default logger should not be the global logger")
+	}
+}
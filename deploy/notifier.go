@@ -0,0 +1,217 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Notification describes the deploy for notifiers.
+type Notification struct {
+	// service, task or scheduled-task.
+	Kind string `json:"kind"`
+	// StatusStarted, StatusSucceeded, StatusFailed or StatusRolledBack.
+	Status Status `json:"status"`
+
+	Cluster                   string    `json:"cluster,omitempty"`
+	Service                   string    `json:"service,omitempty"`
+	Rule                      string    `json:"rule,omitempty"`
+	TaskDefinitionArn         string    `json:"taskDefinitionArn,omitempty"`
+	PreviousTaskDefinitionArn string    `json:"previousTaskDefinitionArn,omitempty"`
+	DeploymentID              string    `json:"deploymentId,omitempty"`
+	TaskArns                  []string  `json:"taskArns,omitempty"`
+	Error                     string    `json:"error,omitempty"`
+	Time                      time.Time `json:"time"`
+}
+
+// Notifier sends notifications of the deploy.
+// An error of the notifier is logged, and it does not fail the deploy.
+type Notifier interface {
+	Notify(notification *Notification) error
+}
+
+// WebhookNotifier posts the notification to the URL as JSON.
+type WebhookNotifier struct {
+	// URL of the webhook.
+	URL string
+
+	// Template of the request body, which is rendered by text/template with Notification.
+	// The json function quotes a value as JSON, for example {"text": {{json .Service}}}.
+	// If this is empty, Notification is posted as JSON.
+	Template string
+
+	// Additional headers of the request.
+	Headers map[string]string
+
+	// HTTP client to post the notification. If this is nil, a client with 10 seconds timeout is used.
+	Client *http.Client
+}
+
+// Notify posts the notification to the webhook.
+func (w *WebhookNotifier) Notify(notification *Notification) error {
+	var body []byte
+	if w.Template == "" {
+		b, err := json.Marshal(notification)
+		if err != nil {
+			return err
+		}
+		body = b
+	} else {
+		b, err := renderTemplate(w.Template, notification)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+	return postJSON(w.Client, w.URL, w.Headers, body)
+}
+
+// SlackNotifier posts the notification to an incoming webhook of Slack.
+type SlackNotifier struct {
+	// URL of the incoming webhook.
+	WebhookURL string
+
+	// Channel to post. If this is empty, the default channel of the webhook is used.
+	Channel string
+
+	// HTTP client to post the notification. If this is nil, a client with 10 seconds timeout is used.
+	Client *http.Client
+}
+
+type slackPayload struct {
+	Channel     string            `json:"channel,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// Notify posts the notification to Slack.
+func (s *SlackNotifier) Notify(notification *Notification) error {
+	body, err := json.Marshal(newSlackPayload(s.Channel, notification))
+	if err != nil {
+		return err
+	}
+	return postJSON(s.Client, s.WebhookURL, nil, body)
+}
+
+// newSlackPayload formats the notification as a message of Slack.
+func newSlackPayload(channel string, n *Notification) *slackPayload {
+	target := n.Service
+	if target == "" {
+		target = n.Rule
+	}
+	if target == "" {
+		target = n.TaskDefinitionArn
+	}
+	color := "#439fe0"
+	switch n.Status {
+	case StatusSucceeded:
+		color = "good"
+	case StatusFailed:
+		color = "danger"
+	case StatusRolledBack:
+		color = "warning"
+	}
+
+	fields := []slackField{}
+	add := func(title, value string, short bool) {
+		if value != "" {
+			fields = append(fields, slackField{Title: title, Value: value, Short: short})
+		}
+	}
+	add("Cluster", n.Cluster, true)
+	add("Deployment", n.DeploymentID, true)
+	add("Task definition", n.TaskDefinitionArn, false)
+	add("Previous task definition", n.PreviousTaskDefinitionArn, false)
+	add("Tasks", strings.Join(n.TaskArns, "\n"), false)
+	add("Error", n.Error, false)
+	return &slackPayload{
+		Channel: channel,
+		Text:    fmt.Sprintf("Deploy of %s %s: %s", n.Kind, target, n.Status),
+		Attachments: []slackAttachment{
+			{Color: color, Fields: fields},
+		},
+	}
+}
+
+// renderTemplate renders the template with the notification.
+func renderTemplate(text string, notification *Notification) ([]byte, error) {
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid webhook template")
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, notification); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// postJSON posts the JSON body to the URL, and returns an error if the status code is not 2xx.
+func postJSON(client *http.Client, url string, headers map[string]string, body []byte) error {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("webhook returns %s", resp.Status)
+	}
+	return nil
+}
+
+// errorString returns the message of the error, or empty if err is nil.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// notifyAll sends the notification to all notifiers, and logs errors of them.
+func notifyAll(notifiers []Notifier, notification *Notification, logger *log.Entry) {
+	if notification.Time.IsZero() {
+		notification.Time = time.Now()
+	}
+	for _, n := range notifiers {
+		if err := n.Notify(notification); err != nil {
+			logger.Errorf("Failed to notify: %v", err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package deploy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/scheduler"
+)
+
+// newWebhookServer returns a server which records request bodies.
+func newWebhookServer(t *testing.T, bodies *[][]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("content type is invalid: %s", r.Header.Get("Content-Type"))
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		*bodies = append(*bodies, b)
+	}))
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	bodies := [][]byte{}
+	server := newWebhookServer(t, &bodies)
+	defer server.Close()
+
+	notification := &Notification{
+		Kind:    "service",
+		Status:  StatusSucceeded,
+		Cluster: "dummy-cluster",
+		Service: "dummy-service",
+	}
+	notifier := &WebhookNotifier{URL: server.URL}
+	if err := notifier.Notify(notification); err != nil {
+		t.Fatal(err)
+	}
+	var posted Notification
+	if err := json.Unmarshal(bodies[0], &posted); err != nil {
+		t.Fatal(err)
+	}
+	if posted.Status != StatusSucceeded || posted.Service != "dummy-service" {
+		t.Errorf("notification is invalid: %s", bodies[0])
+	}
+
+	notifier.Template = `{"text": {{json (printf "%s is %s" .Service .Status)}}}`
+	if err := notifier.Notify(notification); err != nil {
+		t.Fatal(err)
+	}
+	if string(bodies[1]) != `{"text": "dummy-service is succeeded"}` {
+		t.Errorf("rendered template is invalid: %s", bodies[1])
+	}
+
+	notifier.Template = `{{.Unknown}}`
+	if err := notifier.Notify(notification); err == nil {
+		t.Error("invalid template should be error")
+	}
+}
+
+func TestWebhookNotifierError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	notifier := &WebhookNotifier{URL: server.URL}
+	if err := notifier.Notify(&Notification{}); err == nil {
+		t.Error("5xx response should be error")
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	bodies := [][]byte{}
+	server := newWebhookServer(t, &bodies)
+	defer server.Close()
+
+	notifier := &SlackNotifier{WebhookURL: server.URL, Channel: "#deploy"}
+	err := notifier.Notify(&Notification{
+		Kind:         "service",
+		Status:       StatusRolledBack,
+		Cluster:      "dummy-cluster",
+		Service:      "dummy-service",
+		DeploymentID: "ecs-svc/1",
+		Error:        "timeout",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload slackPayload
+	if err := json.Unmarshal(bodies[0], &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Channel != "#deploy" || payload.Text != "Deploy of service dummy-service: rolled_back" {
+		t.Errorf("payload is invalid: %s", bodies[0])
+	}
+	if payload.Attachments[0].Color != "warning" || len(payload.Attachments[0].Fields) != 3 {
+		t.Errorf("attachment is invalid: %s", bodies[0])
+	}
+}
+
+type recordedNotifier struct {
+	notifications []*Notification
+}
+
+func (r *recordedNotifier) Notify(notification *Notification) error {
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+func TestScheduledTaskUpdateNotifies(t *testing.T) {
+	updated := []*scheduler.UpdateScheduleInput{}
+	schedule := &scheduler.GetScheduleOutput{
+		Name: aws.String("dummy"),
+		Target: &scheduler.Target{
+			Arn: aws.String("cluster-arn"),
+			EcsParameters: &scheduler.EcsParameters{
+				TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/dummy:1"),
+			},
+		},
+	}
+	resp := ecs.DescribeTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{
+			TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/dummy:2"),
+		},
+	}
+	notifier := &recordedNotifier{}
+	scheduledTask := &ScheduledTask{
+		awsScheduler:   mockedSchedule{Schedule: schedule, Updated: &updated},
+		TaskDefinition: &TaskDefinition{awsECS: mockedDescribeTaskDefinition{Resp: resp}},
+		Backend:        BackendScheduler,
+		Notifiers:      []Notifier{notifier},
+	}
+	if err := scheduledTask.Update("dummy", aws.String("dummy:2"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifier.notifications) != 2 {
+		t.Fatalf("notifications are invalid: %+v", notifier.notifications)
+	}
+	started, finished := notifier.notifications[0], notifier.notifications[1]
+	if started.Status != StatusStarted || started.Kind != "scheduled-task" || started.Rule != "dummy" {
+		t.Errorf("notification is invalid: %+v", started)
+	}
+	if finished.Status != StatusSucceeded || finished.PreviousTaskDefinitionArn != "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/dummy:1" {
+		t.Errorf("notification is invalid: %+v", finished)
+	}
+}
+
+func TestTaskStartNotifies(t *testing.T) {
+	run := ecs.RunTaskOutput{
+		Tasks: []*ecs.Task{
+			&ecs.Task{TaskArn: aws.String("task-arn")},
+		},
+	}
+	resp := ecs.DescribeTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{
+			TaskDefinitionArn: aws.String("dummy:1"),
+		},
+	}
+	notifier := &recordedNotifier{}
+	task := &Task{
+		awsECS:             mockedRunTask{Run: run},
+		Cluster:            "dummy-cluster",
+		BaseTaskDefinition: "dummy:1",
+		TaskDefinition:     &TaskDefinition{awsECS: mockedDescribeTaskDefinition{Resp: resp}},
+		Count:              1,
+		Notifiers:          []Notifier{notifier},
+	}
+	if _, err := task.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// The tasks are not waited, so only the start is notified.
+	if len(notifier.notifications) != 1 {
+		t.Fatalf("notifications are invalid: %+v", notifier.notifications)
+	}
+	started := notifier.notifications[0]
+	if started.Status != StatusStarted || started.Kind != "task" || len(started.TaskArns) != 1 || started.TaskArns[0] != "task-arn" {
+		t.Errorf("notification is invalid: %+v", started)
+	}
+}
@@ -0,0 +1,232 @@
+package deploy
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	events "github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	eventsiface "github.com/aws/aws-sdk-go/service/cloudwatchevents/cloudwatcheventsiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/scheduler"
+	"github.com/aws/aws-sdk-go/service/scheduler/scheduleriface"
+	log "github.com/sirupsen/logrus"
+)
+
+// Option configures Service, Task, TaskDefinition, ScheduledTask and Exec in the constructors.
+// Options which are not related to the struct are ignored.
+type Option func(*options)
+
+type options struct {
+	session *session.Session
+	profile string
+	region  string
+	verbose bool
+	logger  *log.Entry
+
+	observers []Observer
+	notifiers []Notifier
+	locker    Locker
+
+	ecsClient       ecsiface.ECSAPI
+	eventsClient    eventsiface.CloudWatchEventsAPI
+	schedulerClient scheduleriface.SchedulerAPI
+	dynamoDBClient  dynamodbiface.DynamoDBAPI
+
+	image                string
+	baseTaskDefinition   string
+	timeout              time.Duration
+	enableRollback       bool
+	skipCheckDeployments bool
+
+	container string
+	command   string
+}
+
+// WithSession uses the session to create API clients. If this is not set, a new session is created with the profile and the region.
+func WithSession(sess *session.Session) Option {
+	return func(o *options) {
+		o.session = sess
+	}
+}
+
+// WithProfile sets AWS profile. It is ignored if WithSession is set.
+func WithProfile(profile string) Option {
+	return func(o *options) {
+		o.profile = profile
+	}
+}
+
+// WithRegion sets AWS region. It is ignored if WithSession is set.
+func WithRegion(region string) Option {
+	return func(o *options) {
+		o.region = region
+	}
+}
+
+// WithVerbose enables verbose mode.
+func WithVerbose(verbose bool) Option {
+	return func(o *options) {
+		o.verbose = verbose
+	}
+}
+
+// WithLogger uses the logger instead of the global logger of logrus.
+// Fields like cluster and service are added to the logger.
+func WithLogger(logger *log.Entry) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithObserver adds the observer which receives events while deploying.
+func WithObserver(observer Observer) Option {
+	return func(o *options) {
+		o.observers = append(o.observers, observer)
+	}
+}
+
+// WithNotifier adds the notifier which is notified when the deploy starts and finishes.
+func WithNotifier(notifier Notifier) Option {
+	return func(o *options) {
+		o.notifiers = append(o.notifiers, notifier)
+	}
+}
+
+// WithLocker locks the service while deploying it.
+func WithLocker(locker Locker) Option {
+	return func(o *options) {
+		o.locker = locker
+	}
+}
+
+// WithECSClient uses the ECS API client instead of creating a new one, for example a mock in tests.
+func WithECSClient(client ecsiface.ECSAPI) Option {
+	return func(o *options) {
+		o.ecsClient = client
+	}
+}
+
+// WithCloudWatchEventsClient uses the CloudWatch Events API client in ScheduledTask.
+func WithCloudWatchEventsClient(client eventsiface.CloudWatchEventsAPI) Option {
+	return func(o *options) {
+		o.eventsClient = client
+	}
+}
+
+// WithSchedulerClient uses the EventBridge Scheduler API client in ScheduledTask.
+func WithSchedulerClient(client scheduleriface.SchedulerAPI) Option {
+	return func(o *options) {
+		o.schedulerClient = client
+	}
+}
+
+// WithDynamoDBClient uses the DynamoDB API client in DynamoDBLocker.
+func WithDynamoDBClient(client dynamodbiface.DynamoDBAPI) Option {
+	return func(o *options) {
+		o.dynamoDBClient = client
+	}
+}
+
+// WithImage sets the new image (repository:tag) to deploy the service.
+func WithImage(imageWithTag string) Option {
+	return func(o *options) {
+		o.image = imageWithTag
+	}
+}
+
+// WithBaseTaskDefinition sets the base task definition of the service or the task.
+func WithBaseTaskDefinition(taskDefinition string) Option {
+	return func(o *options) {
+		o.baseTaskDefinition = taskDefinition
+	}
+}
+
+// WithTimeout sets the timeout to wait the service or the task.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithRollback enables rollback of the service when the deploy fails.
+func WithRollback(enable bool) Option {
+	return func(o *options) {
+		o.enableRollback = enable
+	}
+}
+
+// WithSkipCheckDeployments confirms only the new task status when the deploy of the service completes.
+func WithSkipCheckDeployments(skip bool) Option {
+	return func(o *options) {
+		o.skipCheckDeployments = skip
+	}
+}
+
+// WithCommand overrides the command of the container in the task, or sets the command which Exec executes in the container.
+func WithCommand(container, command string) Option {
+	return func(o *options) {
+		o.container = container
+		o.command = command
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// newSession returns the session of the options, or a new session with the profile and the region.
+func (o *options) newSession() *session.Session {
+	if o.session == nil {
+		o.session = session.New(newConfig(o.profile, o.region))
+	}
+	return o.session
+}
+
+// regionName returns the region of the session, or the region which the session will use.
+func (o *options) regionName() string {
+	if o.session != nil {
+		return aws.StringValue(o.session.Config.Region)
+	}
+	return getenv(o.region, "AWS_DEFAULT_REGION")
+}
+
+// newLogger returns the logger of the options, or a new logger which depends on verbose.
+func (o *options) newLogger() *log.Entry {
+	return loggerOrDefault(o.logger, o.verbose)
+}
+
+func (o *options) ecs() ecsiface.ECSAPI {
+	if o.ecsClient != nil {
+		return o.ecsClient
+	}
+	return ecs.New(o.newSession())
+}
+
+func (o *options) events() eventsiface.CloudWatchEventsAPI {
+	if o.eventsClient != nil {
+		return o.eventsClient
+	}
+	return events.New(o.newSession())
+}
+
+func (o *options) scheduler() scheduleriface.SchedulerAPI {
+	if o.schedulerClient != nil {
+		return o.schedulerClient
+	}
+	return scheduler.New(o.newSession())
+}
+
+func (o *options) dynamoDB() dynamodbiface.DynamoDBAPI {
+	if o.dynamoDBClient != nil {
+		return o.dynamoDBClient
+	}
+	return dynamodb.New(o.newSession())
+}
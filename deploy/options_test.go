@@ -0,0 +1,55 @@
+package deploy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestNewServiceWithOptions(t *testing.T) {
+	resp := ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{
+			{ServiceName: aws.String("dummy-service")},
+		},
+	}
+	client := mockedDescribeServices{Resp: resp}
+	service, err := NewServiceWithOptions("dummy-cluster", "dummy-service", WithECSClient(client), WithImage("nginx:stable"), WithBaseTaskDefinition("dummy:1"), WithTimeout(time.Minute), WithRollback(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.NewImage.Repository != "nginx" || service.NewImage.Tag != "stable" {
+		t.Errorf("image is invalid: %v", service.NewImage)
+	}
+	if *service.BaseTaskDefinition != "dummy:1" || service.Timeout != time.Minute || !service.EnableRollback {
+		t.Errorf("service is invalid: %v", service)
+	}
+	if _, ok := service.TaskDefinition.awsECS.(mockedDescribeServices); !ok {
+		t.Error("client is not shared with the task definition")
+	}
+	output, err := service.DescribeService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *output.ServiceName != "dummy-service" {
+		t.Error("ServiceName is invalid")
+	}
+
+	if _, err := NewServiceWithOptions("dummy-cluster", "dummy-service", WithECSClient(client), WithImage("nginx")); err == nil {
+		t.Error("image without tag should be error")
+	}
+}
+
+func TestNewTaskWithOptions(t *testing.T) {
+	task, err := NewTaskWithOptions("dummy-cluster", "dummy:1", WithECSClient(mockedECS{}), WithCommand("app", "echo 'hello world'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Name != "app" || len(task.Command) != 2 || *task.Command[1] != "hello world" {
+		t.Errorf("command is invalid: %v", task.Command)
+	}
+	if task.LaunchType != "EC2" || task.StartedBy != "ecs-goploy" {
+		t.Errorf("task is invalid: %v", task)
+	}
+}
@@ -0,0 +1,65 @@
+package deploy
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/pkg/errors"
+)
+
+// ParsePlacementConstraints converts type:expression strings to placement constraints.
+// Expression can be omitted, for example distinctInstance or memberOf:attribute:ecs.instance-type =~ t3.*.
+func ParsePlacementConstraints(constraints []string) ([]*ecs.PlacementConstraint, error) {
+	var placementConstraints []*ecs.PlacementConstraint
+	for _, c := range constraints {
+		values := strings.SplitN(c, ":", 2)
+		if len(values[0]) == 0 {
+			return nil, errors.Errorf("placement constraint format is wrong: %s", c)
+		}
+		constraint := &ecs.PlacementConstraint{
+			Type: aws.String(values[0]),
+		}
+		if len(values) > 1 {
+			constraint.Expression = aws.String(values[1])
+		}
+		placementConstraints = append(placementConstraints, constraint)
+	}
+	return placementConstraints, nil
+}
+
+// ParsePlacementStrategy converts type:field strings to placement strategy.
+// Field can be omitted, for example random or spread:attribute:ecs.availability-zone.
+func ParsePlacementStrategy(strategies []string) ([]*ecs.PlacementStrategy, error) {
+	var placementStrategy []*ecs.PlacementStrategy
+	for _, s := range strategies {
+		values := strings.SplitN(s, ":", 2)
+		if len(values[0]) == 0 {
+			return nil, errors.Errorf("placement strategy format is wrong: %s", s)
+		}
+		strategy := &ecs.PlacementStrategy{
+			Type: aws.String(values[0]),
+		}
+		if len(values) > 1 {
+			strategy.Field = aws.String(values[1])
+		}
+		placementStrategy = append(placementStrategy, strategy)
+	}
+	return placementStrategy, nil
+}
+
+// ParseTags converts KEY=VALUE strings to tags of ECS resources.
+func ParseTags(tags []string) ([]*ecs.Tag, error) {
+	var ecsTags []*ecs.Tag
+	for _, t := range tags {
+		kv := strings.SplitN(t, "=", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			return nil, errors.Errorf("tag format is wrong: %s", t)
+		}
+		ecsTags = append(ecsTags, &ecs.Tag{
+			Key:   aws.String(kv[0]),
+			Value: aws.String(kv[1]),
+		})
+	}
+	return ecsTags, nil
+}
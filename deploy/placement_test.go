@@ -0,0 +1,51 @@
+package deploy
+
+import (
+	"testing"
+)
+
+func TestParsePlacementConstraints(t *testing.T) {
+	constraints, err := ParsePlacementConstraints([]string{"distinctInstance", "memberOf:attribute:ecs.instance-type =~ t3.*"})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(constraints) != 2 {
+		t.Fatalf("constraints are invalid: %v", constraints)
+	}
+	if *constraints[0].Type != "distinctInstance" || constraints[0].Expression != nil {
+		t.Errorf("constraint is invalid: %v", constraints[0])
+	}
+	if *constraints[1].Type != "memberOf" || *constraints[1].Expression != "attribute:ecs.instance-type =~ t3.*" {
+		t.Errorf("constraint is invalid: %v", constraints[1])
+	}
+}
+
+func TestParsePlacementStrategy(t *testing.T) {
+	strategy, err := ParsePlacementStrategy([]string{"spread:attribute:ecs.availability-zone", "random"})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(strategy) != 2 {
+		t.Fatalf("strategy is invalid: %v", strategy)
+	}
+	if *strategy[0].Type != "spread" || *strategy[0].Field != "attribute:ecs.availability-zone" {
+		t.Errorf("strategy is invalid: %v", strategy[0])
+	}
+	if *strategy[1].Type != "random" || strategy[1].Field != nil {
+		t.Errorf("strategy is invalid: %v", strategy[1])
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tags, err := ParseTags([]string{"team=batch", "cost-center="})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(tags) != 2 || *tags[0].Key != "team" || *tags[0].Value != "batch" || *tags[1].Value != "" {
+		t.Errorf("tags are invalid: %v", tags)
+	}
+
+	if _, err := ParseTags([]string{"team"}); err == nil {
+		t.Error("tag without value should be error")
+	}
+}
@@ -1,11 +1,18 @@
 package deploy
 
 import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	events "github.com/aws/aws-sdk-go/service/cloudwatchevents"
 	eventsiface "github.com/aws/aws-sdk-go/service/cloudwatchevents/cloudwatcheventsiface"
 	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/scheduler/scheduleriface"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
@@ -13,45 +20,180 @@ import (
 // ScheduledTask has target task definition information and client of aws-sdk-go.
 type ScheduledTask struct {
 	awsCloudWatchEvents eventsiface.CloudWatchEventsAPI
+	awsECS              ecsiface.ECSAPI
+	awsScheduler        scheduleriface.SchedulerAPI
 
 	// TaskDefinition struct to call aws API.
 	TaskDefinition *TaskDefinition
 
-	verbose bool
+	// EC2 or FARGATE which updates the targets.
+	// If this is empty, the current launch type of the targets is kept.
+	LaunchType string
+
+	// Capacity provider strategy which updates the targets.
+	// If this is empty, the current strategy of the targets is kept.
+	CapacityProviderStrategy []*ecs.CapacityProviderStrategyItem
+
+	// Platform version of Fargate which updates the targets.
+	PlatformVersion *string
+
+	// Subnet IDs which update the network configuration of the targets.
+	Subnets []*string
+
+	// Security group IDs which update the network configuration of the targets.
+	SecurityGroups []*string
+
+	// ENABLED or DISABLED which updates the network configuration of the targets.
+	AssignPublicIP string
+
+	// IDs of the targets to update. If this is empty, all ECS targets of the rule are updated.
+	TargetIDs []string
+
+	// Task definition family of the targets to update.
+	// If this is empty, the family of the new task definition is used.
+	Family string
+
+	// Update the targets regardless of the task definition family.
+	AnyFamily bool
+
+	// Name or ARN of the event bus of the rules. If this is empty, the default event bus is used.
+	EventBusName string
+
+	// BackendEvents or BackendScheduler. If this is empty, the backend is detected from the name of the scheduled task.
+	Backend string
+
+	// Schedule group of EventBridge Scheduler. If this is empty, the default group is used.
+	ScheduleGroup string
+
+	// Container overrides which are merged into Input of the targets. If this is empty, the current Input is kept.
+	ContainerOverrides []*ecs.ContainerOverride
+
+	// Logger of the scheduled task.
+	Logger *log.Entry
+
+	// Observers receive events while updating the scheduled task. They are passed to the task which is triggered.
+	Observers []Observer
+
+	// Notifiers are notified when Update starts and finishes.
+	Notifiers []Notifier
+
+	// defaultLogger is used when Logger is nil.
+	defaultLogger *log.Entry
+	verbose       bool
 }
 
 // NewScheduledTask returns a nwe ScheduledTask struct, and initialize aws cloudwatchevents API client.
 func NewScheduledTask(profile, region string, verbose bool) *ScheduledTask {
-	awsCloudWatchEvents := events.New(session.New(), newConfig(profile, region))
-	taskDefinition := NewTaskDefinition(profile, region, verbose)
-	if !verbose {
-		log.SetLevel(log.ErrorLevel)
-	}
+	return NewScheduledTaskWithOptions(WithProfile(profile), WithRegion(region), WithVerbose(verbose))
+}
+
+// NewScheduledTaskWithOptions returns a new ScheduledTask struct configured with the options.
+func NewScheduledTaskWithOptions(opts ...Option) *ScheduledTask {
+	o := newOptions(opts)
+	awsECS := o.ecs()
+	logger := o.newLogger()
 	return &ScheduledTask{
-		awsCloudWatchEvents,
-		taskDefinition,
-		verbose,
+		awsCloudWatchEvents: o.events(),
+		awsECS:              awsECS,
+		awsScheduler:        o.scheduler(),
+		TaskDefinition:      &TaskDefinition{awsECS: awsECS, Logger: logger, verbose: o.verbose},
+		Logger:              logger,
+		Observers:           o.observers,
+		Notifiers:           o.notifiers,
+		verbose:             o.verbose,
+	}
+}
+
+func (s *ScheduledTask) logger() *log.Entry {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	if s.defaultLogger == nil {
+		s.defaultLogger = newLogger(s.verbose)
 	}
+	return s.defaultLogger
+}
+
+func (s *ScheduledTask) sendNotification(n *Notification) {
+	n.Kind = "scheduled-task"
+	notifyAll(s.Notifiers, n, s.logger())
+}
+
+// emitUpdated sends EventScheduledTaskUpdated of the result to observers.
+func (s *ScheduledTask) emitUpdated(result *ScheduledTaskResult) {
+	notifyObservers(s.Observers, &Event{
+		Type:                      EventScheduledTaskUpdated,
+		Rule:                      result.Rule,
+		TaskDefinitionArn:         result.TaskDefinitionArn,
+		PreviousTaskDefinitionArn: result.PreviousTaskDefinitionArn,
+	})
 }
 
 // ListsEventTargets list up event targets based on rule name.
 func (s *ScheduledTask) ListsEventTargets(ruleName *string) ([]*events.Target, error) {
+	return s.ListsEventTargetsWithContext(context.Background(), ruleName)
+}
+
+// ListsEventTargetsWithContext is the same as ListsEventTargets with the context.
+func (s *ScheduledTask) ListsEventTargetsWithContext(ctx context.Context, ruleName *string) ([]*events.Target, error) {
+	targets := []*events.Target{}
 	params := &events.ListTargetsByRuleInput{
-		Rule: ruleName,
+		Rule:         ruleName,
+		EventBusName: s.eventBusName(),
 	}
-	resp, err := s.awsCloudWatchEvents.ListTargetsByRule(params)
-	if err != nil {
-		return nil, err
+	for {
+		resp, err := s.awsCloudWatchEvents.ListTargetsByRuleWithContext(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, resp.Targets...)
+		if resp.NextToken == nil {
+			return targets, nil
+		}
+		params.NextToken = resp.NextToken
+	}
+}
+
+// ListRules list up event rules whose name starts with the prefix.
+// If the prefix is empty, all rules in the event bus are returned.
+func (s *ScheduledTask) ListRules(namePrefix string) ([]*events.Rule, error) {
+	return s.ListRulesWithContext(context.Background(), namePrefix)
+}
+
+// ListRulesWithContext is the same as ListRules with the context.
+func (s *ScheduledTask) ListRulesWithContext(ctx context.Context, namePrefix string) ([]*events.Rule, error) {
+	rules := []*events.Rule{}
+	params := &events.ListRulesInput{
+		EventBusName: s.eventBusName(),
+	}
+	if namePrefix != "" {
+		params.NamePrefix = aws.String(namePrefix)
+	}
+	for {
+		resp, err := s.awsCloudWatchEvents.ListRulesWithContext(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, resp.Rules...)
+		if resp.NextToken == nil {
+			return rules, nil
+		}
+		params.NextToken = resp.NextToken
 	}
-	return resp.Targets, nil
 }
 
 // DescribeRule finds an event rule.
 func (s *ScheduledTask) DescribeRule(name string) (*events.DescribeRuleOutput, error) {
+	return s.DescribeRuleWithContext(context.Background(), name)
+}
+
+// DescribeRuleWithContext is the same as DescribeRule with the context.
+func (s *ScheduledTask) DescribeRuleWithContext(ctx context.Context, name string) (*events.DescribeRuleOutput, error) {
 	params := &events.DescribeRuleInput{
-		Name: aws.String(name),
+		Name:         aws.String(name),
+		EventBusName: s.eventBusName(),
 	}
-	resp, err := s.awsCloudWatchEvents.DescribeRule(params)
+	resp, err := s.awsCloudWatchEvents.DescribeRuleWithContext(ctx, params)
 	if err != nil {
 		return nil, err
 	}
@@ -59,48 +201,829 @@ func (s *ScheduledTask) DescribeRule(name string) (*events.DescribeRuleOutput, e
 }
 
 // update updates an event target.
-func (s *ScheduledTask) update(taskCount int64, taskDefinition *ecs.TaskDefinition, baseTarget *events.Target, ruleName *string) error {
-	ecsParameter := &events.EcsParameters{
-		TaskCount:         aws.Int64(taskCount),
-		TaskDefinitionArn: taskDefinition.TaskDefinitionArn,
-	}
+// The new task definition is merged into the current ECS parameters of the target,
+// so launch type, network configuration, tags and Input of the target are kept unless they are specified.
+func (s *ScheduledTask) update(ctx context.Context, taskCount int64, taskDefinition *ecs.TaskDefinition, baseTarget *events.Target, ruleName *string) error {
+	ecsParameter := s.ecsParameters(taskCount, taskDefinition, baseTarget.EcsParameters)
 	target := baseTarget.SetEcsParameters(ecsParameter)
+	input, err := mergeContainerOverridesInput(target.Input, s.ContainerOverrides)
+	if err != nil {
+		return err
+	}
+	target.Input = input
+	return s.putTargets(ctx, ruleName, []*events.Target{target})
+}
+
+// UpdateFamily discovers all rules whose ECS targets run the family, and updates the targets with the task definition.
+// If taskCount is 0, the current count of each target is kept.
+// It continues even if some rules fail, and returns results of all rules which have the targets.
+func (s *ScheduledTask) UpdateFamily(taskCount int64, taskDefinition *ecs.TaskDefinition, family, namePrefix string) ([]*ScheduledTaskResult, error) {
+	return s.UpdateFamilyWithContext(context.Background(), taskCount, taskDefinition, family, namePrefix)
+}
+
+// UpdateFamilyWithContext is the same as UpdateFamily with the context.
+func (s *ScheduledTask) UpdateFamilyWithContext(ctx context.Context, taskCount int64, taskDefinition *ecs.TaskDefinition, family, namePrefix string) ([]*ScheduledTaskResult, error) {
+	rules, err := s.ListRulesWithContext(ctx, namePrefix)
+	if err != nil {
+		return nil, err
+	}
+	selector := *s
+	selector.Family = family
+
+	results := []*ScheduledTaskResult{}
+	failed := 0
+	for _, rule := range rules {
+		targets, err := s.ListsEventTargetsWithContext(ctx, rule.Name)
+		if err != nil {
+			results = append(results, &ScheduledTaskResult{Rule: aws.StringValue(rule.Name), Err: err})
+			failed++
+			continue
+		}
+		selected := selector.SelectTargets(targets, taskDefinition)
+		if len(selected) == 0 {
+			continue
+		}
+		result := &ScheduledTaskResult{
+			Rule:               aws.StringValue(rule.Name),
+			ScheduleExpression: aws.StringValue(rule.ScheduleExpression),
+			TaskDefinitionArn:  aws.StringValue(taskDefinition.TaskDefinitionArn),
+		}
+		for _, target := range selected {
+			result.PreviousTaskDefinitionArn = aws.StringValue(target.EcsParameters.TaskDefinitionArn)
+			if err := s.update(ctx, taskCount, taskDefinition, target, rule.Name); err != nil {
+				result.Err = err
+				failed++
+				break
+			}
+			result.TargetIDs = append(result.TargetIDs, aws.StringValue(target.Id))
+		}
+		if result.Err == nil {
+			s.emitUpdated(result)
+		}
+		results = append(results, result)
+	}
+	if failed > 0 {
+		return results, errors.Errorf("failed to update %d rules", failed)
+	}
+	return results, nil
+}
+
+// ScheduledTaskResult has a result of updating the rule.
+type ScheduledTaskResult struct {
+	// Name of the rule.
+	Rule string
+
+	// Schedule expression of the rule.
+	ScheduleExpression string
+
+	// IDs of the updated targets.
+	TargetIDs []string
+
+	// Task definition which the targets run after the update.
+	TaskDefinitionArn string
+
+	// Task definition which the targets ran before the update.
+	PreviousTaskDefinitionArn string
+
+	// Error which occurred while updating the rule.
+	Err error
+}
+
+// ecsParameters merges the task definition and settings of the scheduled task into the base ECS parameters.
+func (s *ScheduledTask) ecsParameters(taskCount int64, taskDefinition *ecs.TaskDefinition, base *events.EcsParameters) *events.EcsParameters {
+	params := &events.EcsParameters{}
+	if base != nil {
+		copied := *base
+		params = &copied
+	}
+	if taskCount > 0 {
+		params.TaskCount = aws.Int64(taskCount)
+	} else if params.TaskCount == nil {
+		params.TaskCount = aws.Int64(1)
+	}
+	params.TaskDefinitionArn = taskDefinition.TaskDefinitionArn
+
+	// Launch type can not be specified with capacity provider strategy.
+	if len(s.CapacityProviderStrategy) > 0 {
+		params.CapacityProviderStrategy = eventsCapacityProviderStrategy(s.CapacityProviderStrategy)
+		params.LaunchType = nil
+	} else if s.LaunchType != "" {
+		params.LaunchType = aws.String(s.LaunchType)
+		params.CapacityProviderStrategy = nil
+	}
+	if s.PlatformVersion != nil {
+		params.PlatformVersion = s.PlatformVersion
+	}
+
+	if len(s.Subnets) > 0 || len(s.SecurityGroups) > 0 || s.AssignPublicIP != "" {
+		vpc := &events.AwsVpcConfiguration{}
+		if params.NetworkConfiguration != nil && params.NetworkConfiguration.AwsvpcConfiguration != nil {
+			copied := *params.NetworkConfiguration.AwsvpcConfiguration
+			vpc = &copied
+		}
+		if len(s.Subnets) > 0 {
+			vpc.Subnets = s.Subnets
+		}
+		if len(s.SecurityGroups) > 0 {
+			vpc.SecurityGroups = s.SecurityGroups
+		}
+		if s.AssignPublicIP != "" {
+			vpc.AssignPublicIp = aws.String(s.AssignPublicIP)
+		}
+		params.NetworkConfiguration = &events.NetworkConfiguration{
+			AwsvpcConfiguration: vpc,
+		}
+	}
+	return params
+}
+
+// UpdateTargets updates ECS targets related the rule.
+// Targets which are not ECS, or which run another task definition family, are skipped.
+// Please read SelectTargets for more information.
+func (s *ScheduledTask) UpdateTargets(taskCount int64, taskDefinition *ecs.TaskDefinition, name string) error {
+	return s.UpdateTargetsWithContext(context.Background(), taskCount, taskDefinition, name)
+}
+
+// UpdateTargetsWithContext is the same as UpdateTargets with the context.
+func (s *ScheduledTask) UpdateTargetsWithContext(ctx context.Context, taskCount int64, taskDefinition *ecs.TaskDefinition, name string) error {
+	_, err := s.updateTargets(ctx, taskCount, taskDefinition, name)
+	return err
+}
+
+// updateTargets updates ECS targets related the rule, and returns the result.
+func (s *ScheduledTask) updateTargets(ctx context.Context, taskCount int64, taskDefinition *ecs.TaskDefinition, name string) (*ScheduledTaskResult, error) {
+	rule, err := s.DescribeRuleWithContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := s.ListsEventTargetsWithContext(ctx, rule.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := s.SelectTargets(targets, taskDefinition)
+	if len(selected) == 0 {
+		return nil, errors.Errorf("no target of %s matches the task definition family", name)
+	}
+	result := &ScheduledTaskResult{
+		Rule:               aws.StringValue(rule.Name),
+		ScheduleExpression: aws.StringValue(rule.ScheduleExpression),
+		TaskDefinitionArn:  aws.StringValue(taskDefinition.TaskDefinitionArn),
+	}
+	for _, target := range selected {
+		s.logger().WithField("rule", name).Infof("Event target: %s", *target.Arn)
+		result.PreviousTaskDefinitionArn = aws.StringValue(target.EcsParameters.TaskDefinitionArn)
+		err := s.update(ctx, taskCount, taskDefinition, target, rule.Name)
+		if err != nil {
+			result.Err = err
+			return result, err
+		}
+		result.TargetIDs = append(result.TargetIDs, aws.StringValue(target.Id))
+	}
+	s.emitUpdated(result)
+	return result, nil
+}
+
+// SelectTargets returns ECS targets to update with the task definition.
+// If TargetIDs is set, only the targets are selected.
+// Targets whose current task definition belongs to Family are selected.
+// If Family is empty, the family of the given task definition is used, and if AnyFamily is true, the family is not checked.
+func (s *ScheduledTask) SelectTargets(targets []*events.Target, taskDefinition *ecs.TaskDefinition) []*events.Target {
+	family := s.Family
+	if family == "" {
+		family = aws.StringValue(taskDefinition.Family)
+		if family == "" {
+			family = taskDefinitionFamily(aws.StringValue(taskDefinition.TaskDefinitionArn))
+		}
+	}
+
+	selected := []*events.Target{}
+	for _, target := range targets {
+		if target.EcsParameters == nil {
+			s.logger().Infof("Skip the target which is not ECS: %s", aws.StringValue(target.Id))
+			continue
+		}
+		if len(s.TargetIDs) > 0 && !containsString(s.TargetIDs, aws.StringValue(target.Id)) {
+			s.logger().Infof("Skip the target which is not specified: %s", aws.StringValue(target.Id))
+			continue
+		}
+		if !s.AnyFamily && taskDefinitionFamily(aws.StringValue(target.EcsParameters.TaskDefinitionArn)) != family {
+			s.logger().Infof("Skip the target which runs another family: %s", aws.StringValue(target.Id))
+			continue
+		}
+		selected = append(selected, target)
+	}
+	return selected
+}
+
+// taskDefinitionFamily returns family of the task definition from ARN or family:revision.
+func taskDefinitionFamily(taskDefinition string) string {
+	res := strings.Split(taskDefinition, "/")
+	return strings.Split(res[len(res)-1], ":")[0]
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// EnableRule enables the rule of the scheduled task.
+func (s *ScheduledTask) EnableRule(name string) error {
+	return s.EnableRuleWithContext(context.Background(), name)
+}
+
+// EnableRuleWithContext is the same as EnableRule with the context.
+func (s *ScheduledTask) EnableRuleWithContext(ctx context.Context, name string) error {
+	_, err := s.awsCloudWatchEvents.EnableRuleWithContext(ctx, &events.EnableRuleInput{
+		Name:         aws.String(name),
+		EventBusName: s.eventBusName(),
+	})
+	return err
+}
+
+// DisableRule disables the rule of the scheduled task, so the task is not run until the rule is enabled.
+func (s *ScheduledTask) DisableRule(name string) error {
+	return s.DisableRuleWithContext(context.Background(), name)
+}
+
+// DisableRuleWithContext is the same as DisableRule with the context.
+func (s *ScheduledTask) DisableRuleWithContext(ctx context.Context, name string) error {
+	_, err := s.awsCloudWatchEvents.DisableRuleWithContext(ctx, &events.DisableRuleInput{
+		Name:         aws.String(name),
+		EventBusName: s.eventBusName(),
+	})
+	return err
+}
+
+// NewTaskFromTarget returns a Task which runs the same task as the ECS target of the rule.
+// Overrides in Input of the target, network configuration, launch type and placement are copied to the task.
+func (s *ScheduledTask) NewTaskFromTarget(ruleName string, target *events.Target, timeout time.Duration) (*Task, error) {
+	params := target.EcsParameters
+	if params == nil {
+		return nil, errors.Errorf("target %s is not ECS", aws.StringValue(target.Id))
+	}
+	t := &Task{
+		awsECS:             s.awsECS,
+		Cluster:            aws.StringValue(target.Arn),
+		BaseTaskDefinition: aws.StringValue(params.TaskDefinitionArn),
+		TaskDefinition:     s.TaskDefinition,
+		Timeout:            timeout,
+		Count:              aws.Int64Value(params.TaskCount),
+		LaunchType:         aws.StringValue(params.LaunchType),
+		PlatformVersion:    params.PlatformVersion,
+		Group:              params.Group,
+		PropagateTags:      params.PropagateTags,
+		StartedBy:          ruleStartedBy(ruleName),
+		Logger:             s.logger().WithField("rule", ruleName),
+		Observers:          s.Observers,
+		verbose:            s.verbose,
+	}
+	if t.LaunchType == "" && len(params.CapacityProviderStrategy) == 0 {
+		t.LaunchType = "EC2"
+	}
+	for _, c := range params.CapacityProviderStrategy {
+		t.CapacityProviderStrategy = append(t.CapacityProviderStrategy, &ecs.CapacityProviderStrategyItem{
+			CapacityProvider: c.CapacityProvider,
+			Weight:           c.Weight,
+			Base:             c.Base,
+		})
+	}
+	if params.NetworkConfiguration != nil && params.NetworkConfiguration.AwsvpcConfiguration != nil {
+		vpc := params.NetworkConfiguration.AwsvpcConfiguration
+		t.Subnets = vpc.Subnets
+		t.SecurityGroups = vpc.SecurityGroups
+		t.AssignPublicIP = aws.StringValue(vpc.AssignPublicIp)
+	}
+	for _, c := range params.PlacementConstraints {
+		t.PlacementConstraints = append(t.PlacementConstraints, &ecs.PlacementConstraint{
+			Type:       c.Type,
+			Expression: c.Expression,
+		})
+	}
+	for _, p := range params.PlacementStrategy {
+		t.PlacementStrategy = append(t.PlacementStrategy, &ecs.PlacementStrategy{
+			Type:  p.Type,
+			Field: p.Field,
+		})
+	}
+	for _, tag := range params.Tags {
+		t.Tags = append(t.Tags, &ecs.Tag{
+			Key:   tag.Key,
+			Value: tag.Value,
+		})
+	}
+	if target.Input != nil {
+		override, err := parseTaskOverrideInput(*target.Input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Input of target %s is invalid", aws.StringValue(target.Id))
+		}
+		t.ContainerOverrides = override.ContainerOverrides
+		t.CPU = override.Cpu
+		t.Memory = override.Memory
+		t.TaskRoleArn = override.TaskRoleArn
+		t.ExecutionRoleArn = override.ExecutionRoleArn
+	}
+	return t, nil
+}
+
+// ScheduledTaskRule has a schedule and ECS target information to create a scheduled task.
+type ScheduledTaskRule struct {
+	// Name of the rule.
+	Name string
+
+	// Description of the rule.
+	Description string
+
+	// Schedule expression of the rule, cron() or rate().
+	ScheduleExpression string
+
+	// ID of the target in the rule. If this is empty, the rule name is used.
+	TargetID string
+
+	// Name or ARN of ECS cluster to run the task.
+	Cluster string
+
+	// ARN of IAM role which CloudWatch Events uses to run the task.
+	RoleArn string
+
+	// Task definition of the target. Family and revision (family:revision) or full ARN.
+	TaskDefinition string
+
+	// Count of the task.
+	TaskCount int64
+
+	// EC2 or FARGATE. It is ignored if CapacityProviderStrategy is set.
+	LaunchType string
+
+	// Capacity provider strategy to run the task.
+	CapacityProviderStrategy []*ecs.CapacityProviderStrategyItem
+
+	// Platform version of Fargate.
+	PlatformVersion string
+
+	// Subnet IDs for awsvpc network mode.
+	Subnets []*string
+
+	// Security group IDs which are attached to ENI of the task.
+	SecurityGroups []*string
+
+	// ENABLED or DISABLED.
+	AssignPublicIP string
+
+	// Overrides of the containers, which are passed to the task as Input of the target.
+	ContainerOverrides []*ecs.ContainerOverride
+}
+
+// PutRule creates or updates the rule with the schedule expression.
+// If the rule already exists, the state of the rule is kept.
+func (s *ScheduledTask) PutRule(rule *ScheduledTaskRule) (*string, error) {
+	return s.PutRuleWithContext(context.Background(), rule)
+}
+
+// PutRuleWithContext is the same as PutRule with the context.
+func (s *ScheduledTask) PutRuleWithContext(ctx context.Context, rule *ScheduledTaskRule) (*string, error) {
+	if !strings.HasPrefix(rule.ScheduleExpression, "cron(") && !strings.HasPrefix(rule.ScheduleExpression, "rate(") {
+		return nil, errors.Errorf("schedule expression must be cron() or rate(): %s", rule.ScheduleExpression)
+	}
+	params := &events.PutRuleInput{
+		Name:               aws.String(rule.Name),
+		ScheduleExpression: aws.String(rule.ScheduleExpression),
+		EventBusName:       s.eventBusName(),
+	}
+	if rule.Description != "" {
+		params.Description = aws.String(rule.Description)
+	}
+	current, err := s.DescribeRuleWithContext(ctx, rule.Name)
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+	if current != nil {
+		params.State = current.State
+	}
+	resp, err := s.awsCloudWatchEvents.PutRuleWithContext(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return resp.RuleArn, nil
+}
+
+// PutTarget creates or updates the ECS target of the rule.
+// If the target already exists, container overrides of the rule are merged into the current Input of the target.
+func (s *ScheduledTask) PutTarget(rule *ScheduledTaskRule, clusterArn string, taskDefinition *ecs.TaskDefinition) error {
+	return s.PutTargetWithContext(context.Background(), rule, clusterArn, taskDefinition)
+}
+
+// PutTargetWithContext is the same as PutTarget with the context.
+func (s *ScheduledTask) PutTargetWithContext(ctx context.Context, rule *ScheduledTaskRule, clusterArn string, taskDefinition *ecs.TaskDefinition) error {
+	targetID := rule.TargetID
+	if targetID == "" {
+		targetID = rule.Name
+	}
+	count := rule.TaskCount
+	if count <= 0 {
+		count = 1
+	}
+	ecsParameters := &events.EcsParameters{
+		TaskCount:                aws.Int64(count),
+		TaskDefinitionArn:        taskDefinition.TaskDefinitionArn,
+		CapacityProviderStrategy: eventsCapacityProviderStrategy(rule.CapacityProviderStrategy),
+		NetworkConfiguration:     eventsNetworkConfiguration(rule.Subnets, rule.SecurityGroups, rule.AssignPublicIP),
+	}
+	if len(rule.CapacityProviderStrategy) == 0 && rule.LaunchType != "" {
+		ecsParameters.LaunchType = aws.String(rule.LaunchType)
+	}
+	if rule.PlatformVersion != "" {
+		ecsParameters.PlatformVersion = aws.String(rule.PlatformVersion)
+	}
+	target := &events.Target{
+		Id:            aws.String(targetID),
+		Arn:           aws.String(clusterArn),
+		RoleArn:       aws.String(rule.RoleArn),
+		EcsParameters: ecsParameters,
+	}
+	current, err := s.ListsEventTargetsWithContext(ctx, aws.String(rule.Name))
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	for _, c := range current {
+		if aws.StringValue(c.Id) == targetID {
+			target.Input = c.Input
+		}
+	}
+	input, err := mergeContainerOverridesInput(target.Input, rule.ContainerOverrides)
+	if err != nil {
+		return err
+	}
+	target.Input = input
+	return s.putTargets(ctx, aws.String(rule.Name), []*events.Target{target})
+}
+
+// DeleteRule removes all targets of the rule, and deletes the rule.
+// If the rule does not exist, it does nothing.
+func (s *ScheduledTask) DeleteRule(name string) error {
+	return s.DeleteRuleWithContext(context.Background(), name)
+}
+
+// DeleteRuleWithContext is the same as DeleteRule with the context.
+func (s *ScheduledTask) DeleteRuleWithContext(ctx context.Context, name string) error {
+	targets, err := s.ListsEventTargetsWithContext(ctx, aws.String(name))
+	if err != nil {
+		if isNotFound(err) {
+			s.logger().Infof("Rule %s does not exist", name)
+			return nil
+		}
+		return err
+	}
+	if len(targets) > 0 {
+		ids := []*string{}
+		for _, t := range targets {
+			ids = append(ids, t.Id)
+		}
+		resp, err := s.awsCloudWatchEvents.RemoveTargetsWithContext(ctx, &events.RemoveTargetsInput{
+			Rule:         aws.String(name),
+			EventBusName: s.eventBusName(),
+			Ids:          ids,
+		})
+		if err != nil {
+			return err
+		}
+		if aws.Int64Value(resp.FailedEntryCount) > 0 {
+			for _, e := range resp.FailedEntries {
+				s.logger().Errorf("Failed to remove the entry: %+v", *e)
+			}
+			return errors.New("Failed to remove targets")
+		}
+	}
+	_, err = s.awsCloudWatchEvents.DeleteRuleWithContext(ctx, &events.DeleteRuleInput{
+		Name:         aws.String(name),
+		EventBusName: s.eventBusName(),
+	})
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// clusterArn returns ARN of the cluster, because the target of the rule requires ARN.
+func (s *ScheduledTask) clusterArn(ctx context.Context, cluster string) (string, error) {
+	if strings.HasPrefix(cluster, "arn:") {
+		return cluster, nil
+	}
+	resp, err := s.awsECS.DescribeClustersWithContext(ctx, &ecs.DescribeClustersInput{
+		Clusters: []*string{aws.String(cluster)},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Clusters) == 0 {
+		return "", errors.Errorf("cluster %s is not found", cluster)
+	}
+	return aws.StringValue(resp.Clusters[0].ClusterArn), nil
+}
+
+// putTargets calls put-targets API, and handles failed entries.
+func (s *ScheduledTask) putTargets(ctx context.Context, ruleName *string, targets []*events.Target) error {
 	params := &events.PutTargetsInput{
-		Rule: ruleName,
-		Targets: []*events.Target{
-			target,
-		},
+		Rule:         ruleName,
+		Targets:      targets,
+		EventBusName: s.eventBusName(),
 	}
-	resp, err := s.awsCloudWatchEvents.PutTargets(params)
+	resp, err := s.awsCloudWatchEvents.PutTargetsWithContext(ctx, params)
 	if err != nil {
 		return err
 	}
 	if *resp.FailedEntryCount > 0 {
 		for _, e := range resp.FailedEntries {
-			log.Errorf("Failed to update the entry: %+v", *e)
+			s.logger().Errorf("Failed to update the entry: %+v", *e)
 		}
 		return errors.New("Failed to update entries")
 	}
 	return nil
 }
 
-// UpdateTargets updates all event targets related the rule.
-func (s *ScheduledTask) UpdateTargets(taskCount int64, taskDefinition *ecs.TaskDefinition, name string) error {
-	rule, err := s.DescribeRule(name)
+// eventBusName returns the name of the event bus for API parameters.
+// If EventBusName is empty, the default event bus is used.
+func (s *ScheduledTask) eventBusName() *string {
+	if s.EventBusName == "" {
+		return nil
+	}
+	return aws.String(s.EventBusName)
+}
+
+func isNotFound(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == events.ErrCodeResourceNotFoundException
+	}
+	return false
+}
+
+func eventsCapacityProviderStrategy(strategy []*ecs.CapacityProviderStrategyItem) []*events.CapacityProviderStrategyItem {
+	if len(strategy) == 0 {
+		return nil
+	}
+	items := []*events.CapacityProviderStrategyItem{}
+	for _, s := range strategy {
+		items = append(items, &events.CapacityProviderStrategyItem{
+			CapacityProvider: s.CapacityProvider,
+			Weight:           s.Weight,
+			Base:             s.Base,
+		})
+	}
+	return items
+}
+
+func eventsNetworkConfiguration(subnets, securityGroups []*string, assignPublicIP string) *events.NetworkConfiguration {
+	if len(subnets) == 0 {
+		return nil
+	}
+	if assignPublicIP == "" {
+		assignPublicIP = "DISABLED"
+	}
+	return &events.NetworkConfiguration{
+		AwsvpcConfiguration: &events.AwsVpcConfiguration{
+			Subnets:        subnets,
+			SecurityGroups: securityGroups,
+			AssignPublicIp: aws.String(assignPublicIP),
+		},
+	}
+}
+
+// taskOverrideInput is the Input of the target to override containers.
+// Keys of the JSON have to be camel case as same as run-task API.
+type taskOverrideInput struct {
+	ContainerOverrides []containerOverrideInput `json:"containerOverrides"`
+}
+
+type containerOverrideInput struct {
+	Name        string              `json:"name"`
+	Command     []string            `json:"command,omitempty"`
+	Environment []keyValuePairInput `json:"environment,omitempty"`
+}
+
+type keyValuePairInput struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// parseTaskOverrideInput converts the Input JSON of the target to overrides of run-task API.
+// Keys of the JSON are the same as TaskOverride of run-task API, and they are matched with fields of ecs.TaskOverride.
+func parseTaskOverrideInput(input string) (*ecs.TaskOverride, error) {
+	override := &ecs.TaskOverride{}
+	if err := json.Unmarshal([]byte(input), override); err != nil {
+		return nil, err
+	}
+	return override, nil
+}
+
+// containerOverridesInput builds the Input JSON of the target from container overrides.
+func containerOverridesInput(overrides []*ecs.ContainerOverride) (*string, error) {
+	input := taskOverrideInput{
+		ContainerOverrides: []containerOverrideInput{},
+	}
+	for _, o := range overrides {
+		c := containerOverrideInput{
+			Name:    aws.StringValue(o.Name),
+			Command: aws.StringValueSlice(o.Command),
+		}
+		for _, e := range o.Environment {
+			c.Environment = append(c.Environment, keyValuePairInput{
+				Name:  aws.StringValue(e.Name),
+				Value: aws.StringValue(e.Value),
+			})
+		}
+		input.ContainerOverrides = append(input.ContainerOverrides, c)
+	}
+	b, err := json.Marshal(input)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return aws.String(string(b)), nil
+}
+
+// mergeContainerOverridesInput merges container overrides into the Input JSON of the target.
+// Overrides of other containers and other fields in the Input, for example taskRoleArn, are kept.
+// If overrides are empty, the Input is returned as it is.
+func mergeContainerOverridesInput(input *string, overrides []*ecs.ContainerOverride) (*string, error) {
+	if len(overrides) == 0 {
+		return input, nil
+	}
+	if input == nil || *input == "" {
+		return containerOverridesInput(overrides)
+	}
+	current := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(*input), &current); err != nil {
+		return nil, errors.Wrap(err, "Input of the target is not JSON")
 	}
-	targets, err := s.ListsEventTargets(rule.Name)
+	containers := []map[string]json.RawMessage{}
+	if raw, ok := current["containerOverrides"]; ok {
+		if err := json.Unmarshal(raw, &containers); err != nil {
+			return nil, errors.Wrap(err, "containerOverrides of the target is invalid")
+		}
+	}
+	newInput, err := containerOverridesInput(overrides)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	newContainers := map[string][]map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(*newInput), &newContainers); err != nil {
+		return nil, err
+	}
+	for _, n := range newContainers["containerOverrides"] {
+		merged := false
+		for _, c := range containers {
+			if string(c["name"]) == string(n["name"]) {
+				for k, v := range n {
+					c[k] = v
+				}
+				merged = true
+			}
+		}
+		if !merged {
+			containers = append(containers, n)
+		}
 	}
+	b, err := json.Marshal(containers)
+	if err != nil {
+		return nil, err
+	}
+	current["containerOverrides"] = b
+	b, err = json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	return aws.String(string(b)), nil
+}
 
-	for _, target := range targets {
-		log.Infof("Event target: %s", *target.Arn)
-		err := s.update(taskCount, taskDefinition, target, rule.Name)
+// ruleStartedBy returns startedBy of tasks which are run by the rule.
+// Scheduled tasks are started by events-rule/<rule name>, and startedBy is up to 36 characters.
+// So rules whose names share the first 24 characters in the cluster can not be distinguished, and they share the last run.
+func ruleStartedBy(ruleName string) string {
+	startedBy := "events-rule/" + ruleName
+	if len(startedBy) > 36 {
+		startedBy = startedBy[:36]
+	}
+	return startedBy
+}
+
+// ScheduledTaskSummary has the current settings and the last run of an ECS target of the rule.
+type ScheduledTaskSummary struct {
+	Rule               string     `json:"rule"`
+	ScheduleExpression string     `json:"scheduleExpression"`
+	State              string     `json:"state"`
+	TargetID           string     `json:"targetId"`
+	Cluster            string     `json:"cluster"`
+	TaskDefinition     string     `json:"taskDefinition"`
+	TaskCount          int64      `json:"taskCount"`
+	LastRunStatus      string     `json:"lastRunStatus,omitempty"`
+	LastRunAt          *time.Time `json:"lastRunAt,omitempty"`
+	LastRunTaskArn     string     `json:"lastRunTaskArn,omitempty"`
+}
+
+// List returns summaries of all ECS targets of the rules whose name starts with namePrefix.
+// Status of the last run is found from recent tasks which are started by the rule.
+// Because ECS keeps stopped tasks only for a short time, it is empty if the rule has not run recently.
+func (s *ScheduledTask) List(namePrefix string) ([]*ScheduledTaskSummary, error) {
+	return s.ListWithContext(context.Background(), namePrefix)
+}
+
+// ListWithContext is the same as List with the context.
+func (s *ScheduledTask) ListWithContext(ctx context.Context, namePrefix string) ([]*ScheduledTaskSummary, error) {
+	rules, err := s.ListRulesWithContext(ctx, namePrefix)
+	if err != nil {
+		return nil, err
+	}
+	summaries := []*ScheduledTaskSummary{}
+	for _, rule := range rules {
+		if rule.ScheduleExpression == nil {
+			continue
+		}
+		targets, err := s.ListsEventTargetsWithContext(ctx, rule.Name)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		for _, target := range targets {
+			if target.EcsParameters == nil {
+				continue
+			}
+			summary := &ScheduledTaskSummary{
+				Rule:               aws.StringValue(rule.Name),
+				ScheduleExpression: aws.StringValue(rule.ScheduleExpression),
+				State:              aws.StringValue(rule.State),
+				TargetID:           aws.StringValue(target.Id),
+				Cluster:            aws.StringValue(target.Arn),
+				TaskDefinition:     aws.StringValue(target.EcsParameters.TaskDefinitionArn),
+				TaskCount:          aws.Int64Value(target.EcsParameters.TaskCount),
+			}
+			if summary.TaskCount == 0 {
+				summary.TaskCount = 1
+			}
+			last, err := s.lastRunTask(ctx, summary.Cluster, ruleStartedBy(summary.Rule))
+			if err != nil {
+				return nil, err
+			}
+			if last != nil {
+				summary.LastRunStatus = lastRunStatus(last)
+				summary.LastRunAt = last.CreatedAt
+				summary.LastRunTaskArn = aws.StringValue(last.TaskArn)
+			}
+			summaries = append(summaries, summary)
 		}
 	}
-	return nil
+	return summaries, nil
+}
+
+// lastRunTask returns the latest task in the cluster which is started by startedBy.
+// If no task is found, it returns nil.
+func (s *ScheduledTask) lastRunTask(ctx context.Context, cluster, startedBy string) (*ecs.Task, error) {
+	arns := []*string{}
+	for _, status := range []string{"RUNNING", "STOPPED"} {
+		input := &ecs.ListTasksInput{
+			Cluster:       aws.String(cluster),
+			StartedBy:     aws.String(startedBy),
+			DesiredStatus: aws.String(status),
+		}
+		err := s.awsECS.ListTasksPagesWithContext(ctx, input, func(page *ecs.ListTasksOutput, lastPage bool) bool {
+			arns = append(arns, page.TaskArns...)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var last *ecs.Task
+	// DescribeTasks accepts up to 100 tasks.
+	for i := 0; i < len(arns); i += 100 {
+		end := i + 100
+		if end > len(arns) {
+			end = len(arns)
+		}
+		resp, err := s.awsECS.DescribeTasksWithContext(ctx, &ecs.DescribeTasksInput{
+			Cluster: aws.String(cluster),
+			Tasks:   arns[i:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range resp.Tasks {
+			if last == nil || aws.TimeValue(task.CreatedAt).After(aws.TimeValue(last.CreatedAt)) {
+				last = task
+			}
+		}
+	}
+	return last, nil
+}
+
+// lastRunStatus returns RUNNING, SUCCEEDED or FAILED for the task.
+func lastRunStatus(task *ecs.Task) string {
+	if aws.StringValue(task.LastStatus) != "STOPPED" {
+		return aws.StringValue(task.LastStatus)
+	}
+	if NewTaskResults([]*ecs.Task{task})[0].Succeeded() {
+		return "SUCCEEDED"
+	}
+	return "FAILED"
 }
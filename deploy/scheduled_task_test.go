@@ -0,0 +1,527 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	events "github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	eventsiface "github.com/aws/aws-sdk-go/service/cloudwatchevents/cloudwatcheventsiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+type mockedPutTargets struct {
+	eventsiface.CloudWatchEventsAPI
+	Targets *[]*events.Target
+}
+
+func (m mockedPutTargets) ListTargetsByRuleWithContext(ctx aws.Context, in *events.ListTargetsByRuleInput, opts ...request.Option) (*events.ListTargetsByRuleOutput, error) {
+	return &events.ListTargetsByRuleOutput{Targets: []*events.Target{}}, nil
+}
+
+func (m mockedPutTargets) PutTargetsWithContext(ctx aws.Context, in *events.PutTargetsInput, opts ...request.Option) (*events.PutTargetsOutput, error) {
+	*m.Targets = append(*m.Targets, in.Targets...)
+	return &events.PutTargetsOutput{FailedEntryCount: aws.Int64(0)}, nil
+}
+
+func TestPutTarget(t *testing.T) {
+	targets := []*events.Target{}
+	scheduledTask := &ScheduledTask{
+		awsCloudWatchEvents: mockedPutTargets{Targets: &targets},
+	}
+	rule := &ScheduledTaskRule{
+		Name:       "dummy-rule",
+		RoleArn:    "role-arn",
+		LaunchType: "FARGATE",
+		Subnets:    []*string{aws.String("subnet-12abcde")},
+		ContainerOverrides: []*ecs.ContainerOverride{
+			&ecs.ContainerOverride{
+				Name:    aws.String("worker"),
+				Command: []*string{aws.String("bin/batch")},
+			},
+		},
+	}
+	err := scheduledTask.PutTarget(rule, "cluster-arn", &ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("task-definition-arn"),
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(targets) != 1 {
+		t.Fatalf("targets are invalid: %v", targets)
+	}
+	target := targets[0]
+	if *target.Id != "dummy-rule" || *target.Arn != "cluster-arn" || *target.EcsParameters.TaskCount != 1 {
+		t.Errorf("target is invalid: %v", target)
+	}
+	if *target.EcsParameters.LaunchType != "FARGATE" || *target.EcsParameters.NetworkConfiguration.AwsvpcConfiguration.AssignPublicIp != "DISABLED" {
+		t.Errorf("ecs parameters are invalid: %v", target.EcsParameters)
+	}
+	if *target.Input != `{"containerOverrides":[{"name":"worker","command":["bin/batch"]}]}` {
+		t.Errorf("input is invalid: %s", *target.Input)
+	}
+}
+
+func TestPutRuleWithInvalidSchedule(t *testing.T) {
+	scheduledTask := &ScheduledTask{}
+	_, err := scheduledTask.PutRule(&ScheduledTaskRule{
+		Name:               "dummy-rule",
+		ScheduleExpression: "0 * * * *",
+	})
+	if err == nil {
+		t.Error("schedule expression without cron() should be error")
+	}
+}
+
+func TestEcsParameters(t *testing.T) {
+	base := &events.EcsParameters{
+		TaskCount:         aws.Int64(1),
+		TaskDefinitionArn: aws.String("old-task-definition-arn"),
+		LaunchType:        aws.String("FARGATE"),
+		PlatformVersion:   aws.String("1.4.0"),
+		Group:             aws.String("batch"),
+		NetworkConfiguration: &events.NetworkConfiguration{
+			AwsvpcConfiguration: &events.AwsVpcConfiguration{
+				Subnets:        []*string{aws.String("subnet-12abcde")},
+				SecurityGroups: []*string{aws.String("sg-0123asdb")},
+				AssignPublicIp: aws.String("DISABLED"),
+			},
+		},
+	}
+	newTaskDefinition := &ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("new-task-definition-arn"),
+	}
+
+	scheduledTask := &ScheduledTask{}
+	params := scheduledTask.ecsParameters(2, newTaskDefinition, base)
+	if *params.TaskDefinitionArn != "new-task-definition-arn" || *params.TaskCount != 2 {
+		t.Errorf("task definition is invalid: %v", params)
+	}
+	if *params.LaunchType != "FARGATE" || *params.PlatformVersion != "1.4.0" || *params.Group != "batch" {
+		t.Errorf("parameters are not kept: %v", params)
+	}
+	if *params.NetworkConfiguration.AwsvpcConfiguration.Subnets[0] != "subnet-12abcde" {
+		t.Errorf("network configuration is not kept: %v", params.NetworkConfiguration)
+	}
+	if *base.TaskDefinitionArn != "old-task-definition-arn" {
+		t.Error("base parameters should not be changed")
+	}
+
+	scheduledTask = &ScheduledTask{
+		CapacityProviderStrategy: []*ecs.CapacityProviderStrategyItem{
+			&ecs.CapacityProviderStrategyItem{
+				CapacityProvider: aws.String("FARGATE_SPOT"),
+			},
+		},
+		SecurityGroups: []*string{aws.String("sg-2345asdf")},
+	}
+	params = scheduledTask.ecsParameters(1, newTaskDefinition, base)
+	if params.LaunchType != nil || *params.CapacityProviderStrategy[0].CapacityProvider != "FARGATE_SPOT" {
+		t.Errorf("capacity provider is invalid: %v", params)
+	}
+	vpc := params.NetworkConfiguration.AwsvpcConfiguration
+	if *vpc.Subnets[0] != "subnet-12abcde" || *vpc.SecurityGroups[0] != "sg-2345asdf" {
+		t.Errorf("network configuration is invalid: %v", vpc)
+	}
+	if *base.NetworkConfiguration.AwsvpcConfiguration.SecurityGroups[0] != "sg-0123asdb" {
+		t.Error("base network configuration should not be changed")
+	}
+}
+
+func TestEcsParametersKeepTaskCount(t *testing.T) {
+	base := &events.EcsParameters{
+		TaskCount:         aws.Int64(3),
+		TaskDefinitionArn: aws.String("old-task-definition-arn"),
+	}
+	newTaskDefinition := &ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("new-task-definition-arn"),
+	}
+
+	scheduledTask := &ScheduledTask{}
+	params := scheduledTask.ecsParameters(0, newTaskDefinition, base)
+	if *params.TaskCount != 3 {
+		t.Errorf("task count is not kept: %d", *params.TaskCount)
+	}
+	params = scheduledTask.ecsParameters(0, newTaskDefinition, &events.EcsParameters{})
+	if *params.TaskCount != 1 {
+		t.Errorf("default task count is invalid: %d", *params.TaskCount)
+	}
+}
+
+func TestSelectTargets(t *testing.T) {
+	targets := []*events.Target{
+		&events.Target{
+			Id:  aws.String("lambda"),
+			Arn: aws.String("lambda-arn"),
+		},
+		&events.Target{
+			Id:  aws.String("batch"),
+			Arn: aws.String("cluster-arn"),
+			EcsParameters: &events.EcsParameters{
+				TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/my-batch:41"),
+			},
+		},
+		&events.Target{
+			Id:  aws.String("other"),
+			Arn: aws.String("cluster-arn"),
+			EcsParameters: &events.EcsParameters{
+				TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/other:3"),
+			},
+		},
+	}
+	taskDefinition := &ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/my-batch:42"),
+	}
+
+	scheduledTask := &ScheduledTask{}
+	selected := scheduledTask.SelectTargets(targets, taskDefinition)
+	if len(selected) != 1 || *selected[0].Id != "batch" {
+		t.Errorf("selected targets are invalid: %v", selected)
+	}
+
+	scheduledTask = &ScheduledTask{AnyFamily: true}
+	selected = scheduledTask.SelectTargets(targets, taskDefinition)
+	if len(selected) != 2 {
+		t.Errorf("selected targets are invalid: %v", selected)
+	}
+
+	scheduledTask = &ScheduledTask{TargetIDs: []string{"other"}, Family: "other"}
+	selected = scheduledTask.SelectTargets(targets, taskDefinition)
+	if len(selected) != 1 || *selected[0].Id != "other" {
+		t.Errorf("selected targets are invalid: %v", selected)
+	}
+}
+
+type mockedUpdateFamily struct {
+	eventsiface.CloudWatchEventsAPI
+	Rules   []*events.Rule
+	Targets map[string][]*events.Target
+	Updated *[]*events.Target
+}
+
+func (m mockedUpdateFamily) ListRulesWithContext(ctx aws.Context, in *events.ListRulesInput, opts ...request.Option) (*events.ListRulesOutput, error) {
+	return &events.ListRulesOutput{Rules: m.Rules}, nil
+}
+
+func (m mockedUpdateFamily) ListTargetsByRuleWithContext(ctx aws.Context, in *events.ListTargetsByRuleInput, opts ...request.Option) (*events.ListTargetsByRuleOutput, error) {
+	return &events.ListTargetsByRuleOutput{Targets: m.Targets[*in.Rule]}, nil
+}
+
+func (m mockedUpdateFamily) PutTargetsWithContext(ctx aws.Context, in *events.PutTargetsInput, opts ...request.Option) (*events.PutTargetsOutput, error) {
+	*m.Updated = append(*m.Updated, in.Targets...)
+	return &events.PutTargetsOutput{FailedEntryCount: aws.Int64(0)}, nil
+}
+
+func TestUpdateFamily(t *testing.T) {
+	ecsTarget := func(id, taskDefinition string, count int64) *events.Target {
+		return &events.Target{
+			Id:  aws.String(id),
+			Arn: aws.String("cluster-arn"),
+			EcsParameters: &events.EcsParameters{
+				TaskCount:         aws.Int64(count),
+				TaskDefinitionArn: aws.String(taskDefinition),
+			},
+		}
+	}
+	updated := []*events.Target{}
+	scheduledTask := &ScheduledTask{
+		awsCloudWatchEvents: mockedUpdateFamily{
+			Rules: []*events.Rule{
+				&events.Rule{Name: aws.String("daily")},
+				&events.Rule{Name: aws.String("hourly")},
+				&events.Rule{Name: aws.String("other")},
+			},
+			Targets: map[string][]*events.Target{
+				"daily":  []*events.Target{ecsTarget("daily", "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/my-batch:41", 1)},
+				"hourly": []*events.Target{ecsTarget("hourly", "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/my-batch:40", 3)},
+				"other":  []*events.Target{ecsTarget("other", "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/other:1", 1)},
+			},
+			Updated: &updated,
+		},
+	}
+	taskDefinition := &ecs.TaskDefinition{
+		Family:            aws.String("my-batch"),
+		TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/my-batch:42"),
+	}
+	results, err := scheduledTask.UpdateFamily(0, taskDefinition, "my-batch", "")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(results) != 2 || results[0].Rule != "daily" || results[1].Rule != "hourly" {
+		t.Errorf("results are invalid: %+v", results)
+	}
+	if len(updated) != 2 {
+		t.Fatalf("updated targets are invalid: %v", updated)
+	}
+	if *updated[1].EcsParameters.TaskCount != 3 || *updated[1].EcsParameters.TaskDefinitionArn != *taskDefinition.TaskDefinitionArn {
+		t.Errorf("updated target is invalid: %v", updated[1])
+	}
+}
+
+func TestNewTaskFromTarget(t *testing.T) {
+	target := &events.Target{
+		Id:    aws.String("batch"),
+		Arn:   aws.String("cluster-arn"),
+		Input: aws.String(`{"containerOverrides":[{"name":"worker","command":["bin/batch","daily"],"environment":[{"name":"FOO","value":"bar"}]}]}`),
+		EcsParameters: &events.EcsParameters{
+			TaskCount:         aws.Int64(2),
+			TaskDefinitionArn: aws.String("task-definition-arn"),
+			CapacityProviderStrategy: []*events.CapacityProviderStrategyItem{
+				&events.CapacityProviderStrategyItem{
+					CapacityProvider: aws.String("FARGATE_SPOT"),
+				},
+			},
+			NetworkConfiguration: &events.NetworkConfiguration{
+				AwsvpcConfiguration: &events.AwsVpcConfiguration{
+					Subnets:        []*string{aws.String("subnet-12abcde")},
+					AssignPublicIp: aws.String("DISABLED"),
+				},
+			},
+		},
+	}
+	scheduledTask := &ScheduledTask{}
+	task, err := scheduledTask.NewTaskFromTarget("a-very-long-name-of-the-daily-batch-rule", target, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Cluster != "cluster-arn" || task.BaseTaskDefinition != "task-definition-arn" || task.Count != 2 {
+		t.Errorf("task is invalid: %+v", task)
+	}
+	if len(task.CapacityProviderStrategy) != 1 || task.LaunchType != "" || len(task.Subnets) != 1 {
+		t.Errorf("network configuration is invalid: %+v", task)
+	}
+	if len(task.StartedBy) != 36 {
+		t.Errorf("startedBy is invalid: %s", task.StartedBy)
+	}
+	override := task.ContainerOverrides[0]
+	if *override.Name != "worker" || len(override.Command) != 2 || *override.Environment[0].Value != "bar" {
+		t.Errorf("container override is invalid: %v", override)
+	}
+}
+
+func TestNewTaskFromTargetWithTaskOverride(t *testing.T) {
+	target := &events.Target{
+		Id:    aws.String("batch"),
+		Arn:   aws.String("cluster-arn"),
+		Input: aws.String(`{"cpu":"1024","memory":"2048","taskRoleArn":"task-role-arn","executionRoleArn":"execution-role-arn","containerOverrides":[{"name":"worker","cpu":512,"memory":1024}]}`),
+		EcsParameters: &events.EcsParameters{
+			TaskDefinitionArn: aws.String("task-definition-arn"),
+		},
+	}
+	scheduledTask := &ScheduledTask{}
+	task, err := scheduledTask.NewTaskFromTarget("daily-batch", target, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.StringValue(task.CPU) != "1024" || aws.StringValue(task.Memory) != "2048" {
+		t.Errorf("task size is invalid: %v, %v", task.CPU, task.Memory)
+	}
+	if aws.StringValue(task.TaskRoleArn) != "task-role-arn" || aws.StringValue(task.ExecutionRoleArn) != "execution-role-arn" {
+		t.Errorf("roles are invalid: %v, %v", task.TaskRoleArn, task.ExecutionRoleArn)
+	}
+	override := task.ContainerOverrides[0]
+	if *override.Name != "worker" || aws.Int64Value(override.Cpu) != 512 || aws.Int64Value(override.Memory) != 1024 {
+		t.Errorf("container override is invalid: %v", override)
+	}
+}
+
+type mockedDeleteRule struct {
+	eventsiface.CloudWatchEventsAPI
+	EventBusNames *[]string
+}
+
+func (m mockedDeleteRule) ListTargetsByRuleWithContext(ctx aws.Context, in *events.ListTargetsByRuleInput, opts ...request.Option) (*events.ListTargetsByRuleOutput, error) {
+	*m.EventBusNames = append(*m.EventBusNames, aws.StringValue(in.EventBusName))
+	return &events.ListTargetsByRuleOutput{
+		Targets: []*events.Target{&events.Target{Id: aws.String("dummy")}},
+	}, nil
+}
+
+func (m mockedDeleteRule) RemoveTargetsWithContext(ctx aws.Context, in *events.RemoveTargetsInput, opts ...request.Option) (*events.RemoveTargetsOutput, error) {
+	*m.EventBusNames = append(*m.EventBusNames, aws.StringValue(in.EventBusName))
+	return &events.RemoveTargetsOutput{FailedEntryCount: aws.Int64(0)}, nil
+}
+
+func (m mockedDeleteRule) DeleteRuleWithContext(ctx aws.Context, in *events.DeleteRuleInput, opts ...request.Option) (*events.DeleteRuleOutput, error) {
+	*m.EventBusNames = append(*m.EventBusNames, aws.StringValue(in.EventBusName))
+	return &events.DeleteRuleOutput{}, nil
+}
+
+func TestDeleteRuleWithEventBus(t *testing.T) {
+	names := []string{}
+	scheduledTask := &ScheduledTask{
+		awsCloudWatchEvents: mockedDeleteRule{EventBusNames: &names},
+		EventBusName:        "custom-bus",
+	}
+	if err := scheduledTask.DeleteRule("dummy-rule"); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 3 {
+		t.Fatalf("API calls are invalid: %v", names)
+	}
+	for _, n := range names {
+		if n != "custom-bus" {
+			t.Errorf("event bus is not passed: %v", names)
+		}
+	}
+}
+
+type mockedLastRun struct {
+	ecsiface.ECSAPI
+	Tasks map[string][]*ecs.Task
+}
+
+func (m mockedLastRun) ListTasksPagesWithContext(ctx aws.Context, in *ecs.ListTasksInput, fn func(*ecs.ListTasksOutput, bool) bool, opts ...request.Option) error {
+	arns := []*string{}
+	for _, task := range m.Tasks[*in.StartedBy] {
+		if *task.DesiredStatus == *in.DesiredStatus {
+			arns = append(arns, task.TaskArn)
+		}
+	}
+	// ListTasks returns up to 100 tasks in a page.
+	for i := 0; i < len(arns); i += 100 {
+		end := i + 100
+		if end > len(arns) {
+			end = len(arns)
+		}
+		if !fn(&ecs.ListTasksOutput{TaskArns: arns[i:end]}, end == len(arns)) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m mockedLastRun) DescribeTasksWithContext(ctx aws.Context, in *ecs.DescribeTasksInput, opts ...request.Option) (*ecs.DescribeTasksOutput, error) {
+	if len(in.Tasks) > 100 {
+		return nil, errors.New("too many tasks")
+	}
+	tasks := []*ecs.Task{}
+	for _, ts := range m.Tasks {
+		for _, task := range ts {
+			for _, arn := range in.Tasks {
+				if *arn == *task.TaskArn {
+					tasks = append(tasks, task)
+				}
+			}
+		}
+	}
+	return &ecs.DescribeTasksOutput{Tasks: tasks}, nil
+}
+
+func TestList(t *testing.T) {
+	now := time.Now()
+	stoppedTask := func(arn string, createdAt time.Time, exitCode int64) *ecs.Task {
+		return &ecs.Task{
+			TaskArn:       aws.String(arn),
+			DesiredStatus: aws.String("STOPPED"),
+			LastStatus:    aws.String("STOPPED"),
+			CreatedAt:     aws.Time(createdAt),
+			Containers:    []*ecs.Container{&ecs.Container{ExitCode: aws.Int64(exitCode)}},
+		}
+	}
+	scheduledTask := &ScheduledTask{
+		awsCloudWatchEvents: mockedUpdateFamily{
+			Rules: []*events.Rule{
+				&events.Rule{Name: aws.String("batch"), ScheduleExpression: aws.String("rate(1 hour)"), State: aws.String("ENABLED")},
+				&events.Rule{Name: aws.String("pattern"), EventPattern: aws.String("{}")},
+			},
+			Targets: map[string][]*events.Target{
+				"batch": []*events.Target{
+					&events.Target{Id: aws.String("lambda"), Arn: aws.String("lambda-arn")},
+					&events.Target{
+						Id:  aws.String("worker"),
+						Arn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:cluster/dummy"),
+						EcsParameters: &events.EcsParameters{
+							TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/batch:3"),
+						},
+					},
+				},
+			},
+		},
+		awsECS: mockedLastRun{
+			Tasks: map[string][]*ecs.Task{
+				"events-rule/batch": []*ecs.Task{
+					stoppedTask("old-task", now.Add(-2*time.Hour), 0),
+					stoppedTask("last-task", now.Add(-1*time.Hour), 1),
+				},
+			},
+		},
+	}
+	summaries, err := scheduledTask.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 1 {
+		t.Fatalf("summaries are invalid: %v", summaries)
+	}
+	s := summaries[0]
+	if s.Rule != "batch" || s.TargetID != "worker" || s.State != "ENABLED" || s.TaskCount != 1 {
+		t.Errorf("summary is invalid: %+v", s)
+	}
+	if s.LastRunTaskArn != "last-task" || s.LastRunStatus != "FAILED" {
+		t.Errorf("last run is invalid: %+v", s)
+	}
+}
+
+func TestLastRunTaskWithManyTasks(t *testing.T) {
+	now := time.Now()
+	tasks := []*ecs.Task{}
+	for i := 0; i < 250; i++ {
+		tasks = append(tasks, &ecs.Task{
+			TaskArn:       aws.String(fmt.Sprintf("task-%d", i)),
+			DesiredStatus: aws.String("STOPPED"),
+			CreatedAt:     aws.Time(now.Add(time.Duration(i) * time.Minute)),
+		})
+	}
+	scheduledTask := &ScheduledTask{
+		awsECS: mockedLastRun{
+			Tasks: map[string][]*ecs.Task{"events-rule/batch": tasks},
+		},
+	}
+	last, err := scheduledTask.lastRunTask(context.Background(), "cluster-arn", "events-rule/batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last == nil || *last.TaskArn != "task-249" {
+		t.Errorf("last run is invalid: %v", last)
+	}
+}
+
+func TestMergeContainerOverridesInput(t *testing.T) {
+	overrides := []*ecs.ContainerOverride{
+		&ecs.ContainerOverride{
+			Name:    aws.String("worker"),
+			Command: []*string{aws.String("bin/batch"), aws.String("--all")},
+		},
+	}
+	input, err := mergeContainerOverridesInput(nil, overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *input != `{"containerOverrides":[{"name":"worker","command":["bin/batch","--all"]}]}` {
+		t.Errorf("input is invalid: %s", *input)
+	}
+
+	current := `{"taskRoleArn":"role-arn","containerOverrides":[{"name":"worker","command":["bin/old"],"cpu":256},{"name":"sidecar","command":["bin/sidecar"]}]}`
+	input, err = mergeContainerOverridesInput(aws.String(current), overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"containerOverrides":[{"command":["bin/batch","--all"],"cpu":256,"name":"worker"},{"command":["bin/sidecar"],"name":"sidecar"}],"taskRoleArn":"role-arn"}`
+	if *input != expected {
+		t.Errorf("input is invalid: %s", *input)
+	}
+
+	input, err = mergeContainerOverridesInput(aws.String(current), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *input != current {
+		t.Errorf("input should be kept: %s", *input)
+	}
+}
@@ -0,0 +1,189 @@
+package deploy
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/scheduler"
+	"github.com/pkg/errors"
+)
+
+const (
+	// BackendEvents is the backend of scheduled tasks which are CloudWatch Events (EventBridge) rules.
+	BackendEvents = "events"
+	// BackendScheduler is the backend of scheduled tasks which are EventBridge Scheduler schedules.
+	BackendScheduler = "scheduler"
+)
+
+// DetectBackend returns the backend of the scheduled task.
+// If Backend is set, it is returned. Otherwise an EventBridge rule is looked up at first, and then a schedule of EventBridge Scheduler.
+func (s *ScheduledTask) DetectBackend(name string) (string, error) {
+	return s.DetectBackendWithContext(context.Background(), name)
+}
+
+// DetectBackendWithContext is the same as DetectBackend with the context.
+func (s *ScheduledTask) DetectBackendWithContext(ctx context.Context, name string) (string, error) {
+	switch s.Backend {
+	case BackendEvents, BackendScheduler:
+		return s.Backend, nil
+	case "":
+	default:
+		return "", errors.Errorf("backend must be %s or %s: %s", BackendEvents, BackendScheduler, s.Backend)
+	}
+
+	_, err := s.DescribeRuleWithContext(ctx, name)
+	if err == nil {
+		return BackendEvents, nil
+	}
+	if !isNotFound(err) {
+		return "", err
+	}
+	_, err = s.GetScheduleWithContext(ctx, name)
+	if err == nil {
+		s.logger().Infof("%s is a schedule of EventBridge Scheduler", name)
+		return BackendScheduler, nil
+	}
+	if isNotFound(err) {
+		return "", errors.Errorf("rule or schedule %s is not found", name)
+	}
+	return "", err
+}
+
+// GetSchedule finds a schedule of EventBridge Scheduler in ScheduleGroup.
+func (s *ScheduledTask) GetSchedule(name string) (*scheduler.GetScheduleOutput, error) {
+	return s.GetScheduleWithContext(context.Background(), name)
+}
+
+// GetScheduleWithContext is the same as GetSchedule with the context.
+func (s *ScheduledTask) GetScheduleWithContext(ctx context.Context, name string) (*scheduler.GetScheduleOutput, error) {
+	params := &scheduler.GetScheduleInput{
+		Name: aws.String(name),
+	}
+	if s.ScheduleGroup != "" {
+		params.GroupName = aws.String(s.ScheduleGroup)
+	}
+	return s.awsScheduler.GetScheduleWithContext(ctx, params)
+}
+
+// UpdateSchedule updates the ecs:RunTask target of the schedule of EventBridge Scheduler.
+// As same as UpdateTargets, the new task definition is merged into the current ECS parameters,
+// and the target which runs another task definition family is not updated unless AnyFamily is true.
+func (s *ScheduledTask) UpdateSchedule(taskCount int64, taskDefinition *ecs.TaskDefinition, name string) error {
+	return s.UpdateScheduleWithContext(context.Background(), taskCount, taskDefinition, name)
+}
+
+// UpdateScheduleWithContext is the same as UpdateSchedule with the context.
+func (s *ScheduledTask) UpdateScheduleWithContext(ctx context.Context, taskCount int64, taskDefinition *ecs.TaskDefinition, name string) error {
+	_, err := s.updateSchedule(ctx, taskCount, taskDefinition, name)
+	return err
+}
+
+// updateSchedule updates the ecs:RunTask target of the schedule, and returns the result.
+func (s *ScheduledTask) updateSchedule(ctx context.Context, taskCount int64, taskDefinition *ecs.TaskDefinition, name string) (*ScheduledTaskResult, error) {
+	schedule, err := s.GetScheduleWithContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Target == nil || schedule.Target.EcsParameters == nil {
+		return nil, errors.Errorf("target of %s is not ecs:RunTask", name)
+	}
+	family := s.Family
+	if family == "" {
+		family = taskDefinitionFamily(aws.StringValue(taskDefinition.TaskDefinitionArn))
+	}
+	if !s.AnyFamily && taskDefinitionFamily(aws.StringValue(schedule.Target.EcsParameters.TaskDefinitionArn)) != family {
+		return nil, errors.Errorf("target of %s runs another family: %s", name, aws.StringValue(schedule.Target.EcsParameters.TaskDefinitionArn))
+	}
+	s.logger().WithField("schedule", name).Infof("Schedule target: %s", aws.StringValue(schedule.Target.Arn))
+
+	target := *schedule.Target
+	target.EcsParameters = s.schedulerEcsParameters(taskCount, taskDefinition, schedule.Target.EcsParameters)
+	input, err := mergeContainerOverridesInput(target.Input, s.ContainerOverrides)
+	if err != nil {
+		return nil, err
+	}
+	target.Input = input
+	// UpdateSchedule replaces the whole schedule, so all current settings have to be specified.
+	params := &scheduler.UpdateScheduleInput{
+		Name:                       schedule.Name,
+		GroupName:                  schedule.GroupName,
+		ScheduleExpression:         schedule.ScheduleExpression,
+		ScheduleExpressionTimezone: schedule.ScheduleExpressionTimezone,
+		FlexibleTimeWindow:         schedule.FlexibleTimeWindow,
+		Description:                schedule.Description,
+		StartDate:                  schedule.StartDate,
+		EndDate:                    schedule.EndDate,
+		State:                      schedule.State,
+		KmsKeyArn:                  schedule.KmsKeyArn,
+		ActionAfterCompletion:      schedule.ActionAfterCompletion,
+		Target:                     &target,
+	}
+	result := &ScheduledTaskResult{
+		Rule:                      aws.StringValue(schedule.Name),
+		ScheduleExpression:        aws.StringValue(schedule.ScheduleExpression),
+		TaskDefinitionArn:         aws.StringValue(taskDefinition.TaskDefinitionArn),
+		PreviousTaskDefinitionArn: aws.StringValue(schedule.Target.EcsParameters.TaskDefinitionArn),
+	}
+	if _, err := s.awsScheduler.UpdateScheduleWithContext(ctx, params); err != nil {
+		result.Err = err
+		return result, err
+	}
+	s.emitUpdated(result)
+	return result, nil
+}
+
+// schedulerEcsParameters merges the task definition and settings of the scheduled task into the base ECS parameters of the schedule.
+func (s *ScheduledTask) schedulerEcsParameters(taskCount int64, taskDefinition *ecs.TaskDefinition, base *scheduler.EcsParameters) *scheduler.EcsParameters {
+	params := &scheduler.EcsParameters{}
+	if base != nil {
+		copied := *base
+		params = &copied
+	}
+	if taskCount > 0 {
+		params.TaskCount = aws.Int64(taskCount)
+	} else if params.TaskCount == nil {
+		params.TaskCount = aws.Int64(1)
+	}
+	params.TaskDefinitionArn = taskDefinition.TaskDefinitionArn
+
+	// Launch type can not be specified with capacity provider strategy.
+	if len(s.CapacityProviderStrategy) > 0 {
+		params.CapacityProviderStrategy = []*scheduler.CapacityProviderStrategyItem{}
+		for _, c := range s.CapacityProviderStrategy {
+			params.CapacityProviderStrategy = append(params.CapacityProviderStrategy, &scheduler.CapacityProviderStrategyItem{
+				CapacityProvider: c.CapacityProvider,
+				Weight:           c.Weight,
+				Base:             c.Base,
+			})
+		}
+		params.LaunchType = nil
+	} else if s.LaunchType != "" {
+		params.LaunchType = aws.String(s.LaunchType)
+		params.CapacityProviderStrategy = nil
+	}
+	if s.PlatformVersion != nil {
+		params.PlatformVersion = s.PlatformVersion
+	}
+
+	if len(s.Subnets) > 0 || len(s.SecurityGroups) > 0 || s.AssignPublicIP != "" {
+		vpc := &scheduler.AwsVpcConfiguration{}
+		if params.NetworkConfiguration != nil && params.NetworkConfiguration.AwsvpcConfiguration != nil {
+			copied := *params.NetworkConfiguration.AwsvpcConfiguration
+			vpc = &copied
+		}
+		if len(s.Subnets) > 0 {
+			vpc.Subnets = s.Subnets
+		}
+		if len(s.SecurityGroups) > 0 {
+			vpc.SecurityGroups = s.SecurityGroups
+		}
+		if s.AssignPublicIP != "" {
+			vpc.AssignPublicIp = aws.String(s.AssignPublicIP)
+		}
+		params.NetworkConfiguration = &scheduler.NetworkConfiguration{
+			AwsvpcConfiguration: vpc,
+		}
+	}
+	return params
+}
@@ -0,0 +1,117 @@
+package deploy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	events "github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	eventsiface "github.com/aws/aws-sdk-go/service/cloudwatchevents/cloudwatcheventsiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/scheduler"
+	"github.com/aws/aws-sdk-go/service/scheduler/scheduleriface"
+)
+
+type mockedRuleNotFound struct {
+	eventsiface.CloudWatchEventsAPI
+}
+
+func (m mockedRuleNotFound) DescribeRuleWithContext(ctx aws.Context, in *events.DescribeRuleInput, opts ...request.Option) (*events.DescribeRuleOutput, error) {
+	return nil, awserr.New("ResourceNotFoundException", "Rule does not exist", nil)
+}
+
+type mockedSchedule struct {
+	scheduleriface.SchedulerAPI
+	Schedule *scheduler.GetScheduleOutput
+	Updated  *[]*scheduler.UpdateScheduleInput
+}
+
+func (m mockedSchedule) GetScheduleWithContext(ctx aws.Context, in *scheduler.GetScheduleInput, opts ...request.Option) (*scheduler.GetScheduleOutput, error) {
+	return m.Schedule, nil
+}
+
+func (m mockedSchedule) UpdateScheduleWithContext(ctx aws.Context, in *scheduler.UpdateScheduleInput, opts ...request.Option) (*scheduler.UpdateScheduleOutput, error) {
+	*m.Updated = append(*m.Updated, in)
+	return &scheduler.UpdateScheduleOutput{ScheduleArn: aws.String("schedule-arn")}, nil
+}
+
+func TestDetectBackend(t *testing.T) {
+	scheduledTask := &ScheduledTask{
+		awsCloudWatchEvents: mockedRuleNotFound{},
+		awsScheduler:        mockedSchedule{Schedule: &scheduler.GetScheduleOutput{Name: aws.String("dummy")}},
+	}
+	backend, err := scheduledTask.DetectBackend("dummy")
+	if err != nil {
+		t.Error(err)
+	}
+	if backend != BackendScheduler {
+		t.Errorf("backend is invalid: %s", backend)
+	}
+
+	scheduledTask.Backend = "invalid"
+	if _, err := scheduledTask.DetectBackend("dummy"); err == nil {
+		t.Error("invalid backend should be error")
+	}
+}
+
+func TestUpdateSchedule(t *testing.T) {
+	updated := []*scheduler.UpdateScheduleInput{}
+	schedule := &scheduler.GetScheduleOutput{
+		Name:               aws.String("dummy"),
+		GroupName:          aws.String("default"),
+		ScheduleExpression: aws.String("cron(0 12 * * ? *)"),
+		FlexibleTimeWindow: &scheduler.FlexibleTimeWindow{Mode: aws.String("OFF")},
+		State:              aws.String("ENABLED"),
+		StartDate:          aws.Time(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Target: &scheduler.Target{
+			Arn:     aws.String("cluster-arn"),
+			RoleArn: aws.String("role-arn"),
+			Input:   aws.String(`{"containerOverrides":[]}`),
+			EcsParameters: &scheduler.EcsParameters{
+				TaskCount:         aws.Int64(2),
+				TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/dummy:1"),
+				LaunchType:        aws.String("FARGATE"),
+				NetworkConfiguration: &scheduler.NetworkConfiguration{
+					AwsvpcConfiguration: &scheduler.AwsVpcConfiguration{
+						Subnets: []*string{aws.String("subnet-12abcde")},
+					},
+				},
+			},
+		},
+	}
+	scheduledTask := &ScheduledTask{
+		awsScheduler: mockedSchedule{Schedule: schedule, Updated: &updated},
+	}
+	taskDefinition := &ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/dummy:2"),
+	}
+	if err := scheduledTask.UpdateSchedule(0, taskDefinition, "dummy"); err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 1 {
+		t.Fatalf("schedule is not updated: %v", updated)
+	}
+	in := updated[0]
+	if *in.ScheduleExpression != "cron(0 12 * * ? *)" || *in.State != "ENABLED" || *in.FlexibleTimeWindow.Mode != "OFF" || in.StartDate == nil {
+		t.Errorf("settings of the schedule are not kept: %v", in)
+	}
+	if *in.Target.RoleArn != "role-arn" || *in.Target.Input != `{"containerOverrides":[]}` {
+		t.Errorf("target is invalid: %v", in.Target)
+	}
+	params := in.Target.EcsParameters
+	if *params.TaskDefinitionArn != *taskDefinition.TaskDefinitionArn || *params.TaskCount != 2 || *params.LaunchType != "FARGATE" {
+		t.Errorf("ecs parameters are invalid: %v", params)
+	}
+	if *params.NetworkConfiguration.AwsvpcConfiguration.Subnets[0] != "subnet-12abcde" {
+		t.Errorf("network configuration is not kept: %v", params.NetworkConfiguration)
+	}
+
+	other := &ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/other:1"),
+	}
+	if err := scheduledTask.UpdateSchedule(0, other, "dummy"); err == nil {
+		t.Error("schedule which runs another family should not be updated")
+	}
+}
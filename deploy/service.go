@@ -5,7 +5,6 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
 	"github.com/pkg/errors"
@@ -42,21 +41,65 @@ type Service struct {
 	// If this flag is true, confirm service deployments status.
 	SkipCheckDeployments bool
 
-	verbose bool
+	// Capacity provider strategy which updates the service.
+	// If this is empty, the current strategy of the service is kept.
+	CapacityProviderStrategy []*ecs.CapacityProviderStrategyItem
+
+	// Platform version of Fargate which updates the service.
+	PlatformVersion *string
+
+	// Logger of the service.
+	Logger *log.Entry
+
+	// Observers receive events while deploying.
+	Observers []Observer
+
+	// Notifiers are notified when Deploy starts and finishes.
+	Notifiers []Notifier
+
+	// Locker prevents concurrent deploys of the service. If this is nil, the service is not locked.
+	Locker Locker
+
+	// Wait time for the lock which is held by another deploy. If this is zero, Deploy fails immediately with ErrLocked.
+	LockTimeout time.Duration
+
+	// PreDeploy is called with the current service after the service is locked. If it returns an error, the deploy is aborted.
+	PreDeploy func(ctx context.Context, service *ecs.Service) error
+
+	// defaultLogger is used when Logger is nil.
+	defaultLogger *log.Entry
+	verbose       bool
 }
 
 // NewService returns a new Service struct, and initialize aws ecs API client.
 // Separates imageWithTag into repository and tag, then sets a NewImage for deploy.
 func NewService(cluster, name, imageWithTag string, baseTaskDefinition *string, timeout time.Duration, enableRollback bool, skipCheckDeployments bool, profile, region string, verbose bool) (*Service, error) {
-	awsECS := ecs.New(session.New(), newConfig(profile, region))
-	taskDefinition := NewTaskDefinition(profile, region, verbose)
-	if !verbose {
-		log.SetLevel(log.ErrorLevel)
+	opts := []Option{
+		WithImage(imageWithTag),
+		WithTimeout(timeout),
+		WithRollback(enableRollback),
+		WithSkipCheckDeployments(skipCheckDeployments),
+		WithProfile(profile),
+		WithRegion(region),
+		WithVerbose(verbose),
 	}
+	if baseTaskDefinition != nil {
+		opts = append(opts, WithBaseTaskDefinition(*baseTaskDefinition))
+	}
+	return NewServiceWithOptions(cluster, name, opts...)
+}
+
+// NewServiceWithOptions returns a new Service struct configured with the options.
+// For example:
+//
+//	s, err := ecsdeploy.NewServiceWithOptions("cluster", "service-name", ecsdeploy.WithImage("nginx:stable"), ecsdeploy.WithECSClient(client))
+func NewServiceWithOptions(cluster, name string, opts ...Option) (*Service, error) {
+	o := newOptions(opts)
+	awsECS := o.ecs()
+	logger := o.newLogger().WithFields(log.Fields{"cluster": cluster, "service": name})
 	var newImage *Image
-	if len(imageWithTag) > 0 {
-		var err error
-		repository, tag, err := divideImageAndTag(imageWithTag)
+	if len(o.image) > 0 {
+		repository, tag, err := divideImageAndTag(o.image)
 		if err != nil {
 			return nil, err
 		}
@@ -65,29 +108,65 @@ func NewService(cluster, name, imageWithTag string, baseTaskDefinition *string,
 			*tag,
 		}
 	}
+	var baseTaskDefinition *string
+	if len(o.baseTaskDefinition) > 0 {
+		baseTaskDefinition = aws.String(o.baseTaskDefinition)
+	}
 	return &Service{
-		awsECS,
-		cluster,
-		name,
-		baseTaskDefinition,
-		taskDefinition,
-		newImage,
-		timeout,
-		enableRollback,
-		skipCheckDeployments,
-		verbose,
+		awsECS:               awsECS,
+		Cluster:              cluster,
+		Name:                 name,
+		BaseTaskDefinition:   baseTaskDefinition,
+		TaskDefinition:       &TaskDefinition{awsECS: awsECS, Logger: logger, verbose: o.verbose},
+		NewImage:             newImage,
+		Timeout:              o.timeout,
+		EnableRollback:       o.enableRollback,
+		SkipCheckDeployments: o.skipCheckDeployments,
+		Logger:               logger,
+		Observers:            o.observers,
+		Notifiers:            o.notifiers,
+		Locker:               o.locker,
+		verbose:              o.verbose,
 	}, nil
 }
 
+func (s *Service) logger() *log.Entry {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	if s.defaultLogger == nil {
+		s.defaultLogger = newLogger(s.verbose)
+	}
+	return s.defaultLogger
+}
+
+func (s *Service) emit(event *Event) {
+	event.Cluster = s.Cluster
+	event.Service = s.Name
+	notifyObservers(s.Observers, event)
+}
+
+func (s *Service) sendNotification(n *Notification) {
+	n.Kind = "service"
+	n.Cluster = s.Cluster
+	n.Service = s.Name
+	notifyAll(s.Notifiers, n, s.logger())
+}
+
 // DescribeService gets a current service in the cluster.
 func (s *Service) DescribeService() (*ecs.Service, error) {
+	return s.DescribeServiceWithContext(context.Background())
+}
+
+// DescribeServiceWithContext is the same as DescribeService with the context.
+func (s *Service) DescribeServiceWithContext(ctx context.Context) (*ecs.Service, error) {
 	params := &ecs.DescribeServicesInput{
 		Services: []*string{
 			aws.String(s.Name),
 		},
 		Cluster: aws.String(s.Cluster),
 	}
-	resp, err := s.awsECS.DescribeServices(params)
+	resp, err := s.awsECS.DescribeServicesWithContext(ctx, params)
 	if err != nil {
 		return nil, err
 	}
@@ -97,6 +176,19 @@ func (s *Service) DescribeService() (*ecs.Service, error) {
 
 // UpdateService updates the service with a new task definition, and wait during update action.
 func (s *Service) UpdateService(service *ecs.Service, taskDefinition *ecs.TaskDefinition) error {
+	return s.UpdateServiceWithContext(context.Background(), service, taskDefinition)
+}
+
+// UpdateServiceWithContext is the same as UpdateService with the context.
+// Timeout of the service is applied in addition to the deadline of the context.
+func (s *Service) UpdateServiceWithContext(ctx context.Context, service *ecs.Service, taskDefinition *ecs.TaskDefinition) error {
+	_, err := s.updateService(ctx, service, taskDefinition)
+	return err
+}
+
+// updateService updates the service as same as UpdateService, and returns the updated service.
+// If update-service API fails, the updated service is nil.
+func (s *Service) updateService(ctx context.Context, service *ecs.Service, taskDefinition *ecs.TaskDefinition) (*ecs.Service, error) {
 	params := &ecs.UpdateServiceInput{}
 	if *service.SchedulingStrategy == "DAEMON" {
 		// If the service type is DAEMON, we can not specify desired count.
@@ -115,27 +207,49 @@ func (s *Service) UpdateService(service *ecs.Service, taskDefinition *ecs.TaskDe
 			TaskDefinition:          taskDefinition.TaskDefinitionArn,
 		}
 	}
-	resp, err := s.awsECS.UpdateService(params)
+	if len(s.CapacityProviderStrategy) > 0 {
+		params.CapacityProviderStrategy = s.CapacityProviderStrategy
+	}
+	params.PlatformVersion = s.PlatformVersion
+	resp, err := s.awsECS.UpdateServiceWithContext(ctx, params)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	newService := resp.Service
+	deploymentID := primaryDeploymentID(newService)
+	logger := s.logger().WithField("deployment", deploymentID)
+	logger.Infof("Service is updated: %s", aws.StringValue(taskDefinition.TaskDefinitionArn))
+	s.emit(&Event{
+		Type:              EventServiceUpdated,
+		TaskDefinitionArn: aws.StringValue(taskDefinition.TaskDefinitionArn),
+		DeploymentID:      deploymentID,
+	})
 	if *newService.DesiredCount <= 0 {
-		return nil
+		return newService, nil
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
 	defer cancel()
-	return s.waitUpdating(ctx, taskDefinition)
+	return newService, s.waitUpdating(ctx, taskDefinition, logger)
+}
+
+// primaryDeploymentID returns ID of the primary deployment of the service.
+func primaryDeploymentID(service *ecs.Service) string {
+	for _, d := range service.Deployments {
+		if aws.StringValue(d.Status) == "PRIMARY" {
+			return aws.StringValue(d.Id)
+		}
+	}
+	return ""
 }
 
 // waitUpdating waits the new task definition is deployed.
-func (s *Service) waitUpdating(ctx context.Context, newTaskDefinition *ecs.TaskDefinition) error {
-	log.Info("Waiting for new task running...")
+func (s *Service) waitUpdating(ctx context.Context, newTaskDefinition *ecs.TaskDefinition, logger *log.Entry) error {
+	logger.Info("Waiting for new task running...")
 	errCh := make(chan error, 1)
 	done := make(chan struct{}, 1)
 	go func() {
-		err := s.waitSwitchTask(newTaskDefinition)
+		err := s.waitSwitchTask(ctx, newTaskDefinition)
 		if err != nil {
 			errCh <- err
 		}
@@ -147,31 +261,39 @@ func (s *Service) waitUpdating(ctx context.Context, newTaskDefinition *ecs.TaskD
 			return err
 		}
 	case <-done:
-		log.Info("New task is running")
+		logger.Info("New task is running")
+		s.emit(&Event{
+			Type:              EventNewTaskRunning,
+			TaskDefinitionArn: aws.StringValue(newTaskDefinition.TaskDefinitionArn),
+		})
 	case <-ctx.Done():
-		return errors.New("process timeout")
+		return contextError(ctx)
 	}
 
 	return nil
 }
 
-func (s *Service) waitSwitchTask(newTaskDefinition *ecs.TaskDefinition) error {
+// waitSwitchTask polls the service until the new task definition is deployed.
+// It returns when the context is done, so the polling goroutine does not leak.
+func (s *Service) waitSwitchTask(ctx context.Context, newTaskDefinition *ecs.TaskDefinition) error {
 	for {
-		time.Sleep(5 * time.Second)
+		if err := sleep(ctx, 5*time.Second); err != nil {
+			return err
+		}
 
-		service, err := s.DescribeService()
+		service, err := s.DescribeServiceWithContext(ctx)
 		if err != nil {
 			return err
 		}
-		if s.checkCompleteDeploy(service, newTaskDefinition) {
+		if s.checkCompleteDeploy(ctx, service, newTaskDefinition) {
 			return nil
 		}
 	}
 }
 
-func (s *Service) checkCompleteDeploy(service *ecs.Service, newTaskDefinition *ecs.TaskDefinition) bool {
+func (s *Service) checkCompleteDeploy(ctx context.Context, service *ecs.Service, newTaskDefinition *ecs.TaskDefinition) bool {
 	if s.SkipCheckDeployments {
-		return s.checkNewTaskRunning(service, newTaskDefinition)
+		return s.checkNewTaskRunning(ctx, service, newTaskDefinition)
 	}
 	return s.checkDeployments(service.Deployments, newTaskDefinition)
 }
@@ -188,24 +310,24 @@ func (s *Service) checkDeployments(deployments []*ecs.Deployment, newTaskDefinit
 	return false
 }
 
-func (s *Service) checkNewTaskRunning(service *ecs.Service, newTaskDefinition *ecs.TaskDefinition) bool {
+func (s *Service) checkNewTaskRunning(ctx context.Context, service *ecs.Service, newTaskDefinition *ecs.TaskDefinition) bool {
 	input := &ecs.ListTasksInput{
 		Cluster:       service.ClusterArn,
 		ServiceName:   service.ServiceName,
 		DesiredStatus: aws.String("RUNNING"),
 	}
-	runningTasks, err := s.awsECS.ListTasks(input)
+	runningTasks, err := s.awsECS.ListTasksWithContext(ctx, input)
 	if err != nil {
-		log.Error(err)
+		s.logger().Error(err)
 		return false
 	}
 	params := &ecs.DescribeTasksInput{
 		Cluster: service.ClusterArn,
 		Tasks:   runningTasks.TaskArns,
 	}
-	resp, err := s.awsECS.DescribeTasks(params)
+	resp, err := s.awsECS.DescribeTasksWithContext(ctx, params)
 	if err != nil {
-		log.Error(err)
+		s.logger().Error(err)
 		return false
 	}
 	for _, task := range resp.Tasks {
@@ -220,6 +342,11 @@ func (s *Service) checkNewTaskRunning(service *ecs.Service, newTaskDefinition *e
 // Rollback updates the service with current task definition.
 // This method call update-service API and does not wait for execution to end.
 func (s *Service) Rollback(service *ecs.Service, currentTaskDefinition *ecs.TaskDefinition) error {
+	return s.RollbackWithContext(context.Background(), service, currentTaskDefinition)
+}
+
+// RollbackWithContext is the same as Rollback with the context.
+func (s *Service) RollbackWithContext(ctx context.Context, service *ecs.Service, currentTaskDefinition *ecs.TaskDefinition) error {
 	if currentTaskDefinition == nil {
 		return errors.New("old task definition is not exist")
 	}
@@ -230,10 +357,23 @@ func (s *Service) Rollback(service *ecs.Service, currentTaskDefinition *ecs.Task
 		DesiredCount:            service.DesiredCount,
 		TaskDefinition:          currentTaskDefinition.TaskDefinitionArn,
 	}
-	_, err := s.awsECS.UpdateService(params)
+	// Restore the capacity provider strategy and platform version if the deploy changed them.
+	if len(s.CapacityProviderStrategy) > 0 {
+		params.CapacityProviderStrategy = service.CapacityProviderStrategy
+		if len(service.CapacityProviderStrategy) == 0 {
+			// The service used the launch type. An empty strategy removes the strategy which the deploy set,
+			// and the new deployment is forced because ECS does not switch running tasks by itself.
+			params.CapacityProviderStrategy = []*ecs.CapacityProviderStrategyItem{}
+			params.ForceNewDeployment = aws.Bool(true)
+		}
+	}
+	if s.PlatformVersion != nil {
+		params.PlatformVersion = service.PlatformVersion
+	}
+	_, err := s.awsECS.UpdateServiceWithContext(ctx, params)
 	if err != nil {
 		return err
 	}
-	log.Info("Rolled back")
+	s.logger().Info("Rolled back")
 	return nil
 }
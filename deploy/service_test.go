@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
 )
@@ -14,7 +15,7 @@ type mockedDescribeServices struct {
 	Resp ecs.DescribeServicesOutput
 }
 
-func (m mockedDescribeServices) DescribeServices(in *ecs.DescribeServicesInput) (*ecs.DescribeServicesOutput, error) {
+func (m mockedDescribeServices) DescribeServicesWithContext(ctx aws.Context, in *ecs.DescribeServicesInput, opts ...request.Option) (*ecs.DescribeServicesOutput, error) {
 	return &m.Resp, nil
 }
 
@@ -47,11 +48,11 @@ type mockedUpdateService struct {
 	Describe ecs.DescribeServicesOutput
 }
 
-func (m mockedUpdateService) UpdateService(in *ecs.UpdateServiceInput) (*ecs.UpdateServiceOutput, error) {
+func (m mockedUpdateService) UpdateServiceWithContext(ctx aws.Context, in *ecs.UpdateServiceInput, opts ...request.Option) (*ecs.UpdateServiceOutput, error) {
 	return &m.Update, nil
 }
 
-func (m mockedUpdateService) DescribeServices(in *ecs.DescribeServicesInput) (*ecs.DescribeServicesOutput, error) {
+func (m mockedUpdateService) DescribeServicesWithContext(ctx aws.Context, in *ecs.DescribeServicesInput, opts ...request.Option) (*ecs.DescribeServicesOutput, error) {
 	return &m.Describe, nil
 }
 
@@ -98,3 +99,44 @@ func TestUpdateService(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+type mockedRollbackService struct {
+	ecsiface.ECSAPI
+	Input *ecs.UpdateServiceInput
+}
+
+func (m *mockedRollbackService) UpdateServiceWithContext(ctx aws.Context, in *ecs.UpdateServiceInput, opts ...request.Option) (*ecs.UpdateServiceOutput, error) {
+	m.Input = in
+	return &ecs.UpdateServiceOutput{}, nil
+}
+
+func TestRollbackToLaunchType(t *testing.T) {
+	s := &ecs.Service{
+		ServiceName:  aws.String("dummy-service"),
+		DesiredCount: aws.Int64(1),
+		LaunchType:   aws.String("FARGATE"),
+	}
+	currentTaskDefinition := &ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("current-task-definition-arn"),
+	}
+	client := &mockedRollbackService{}
+	service := &Service{
+		awsECS: client,
+		CapacityProviderStrategy: []*ecs.CapacityProviderStrategyItem{
+			{CapacityProvider: aws.String("FARGATE_SPOT"), Weight: aws.Int64(1)},
+		},
+	}
+
+	if err := service.Rollback(s, currentTaskDefinition); err != nil {
+		t.Fatal(err)
+	}
+	if client.Input.CapacityProviderStrategy == nil || len(client.Input.CapacityProviderStrategy) != 0 {
+		t.Errorf("capacity provider strategy is not cleared: %v", client.Input.CapacityProviderStrategy)
+	}
+	if !aws.BoolValue(client.Input.ForceNewDeployment) {
+		t.Error("new deployment is not forced")
+	}
+	if aws.StringValue(client.Input.TaskDefinition) != "current-task-definition-arn" {
+		t.Errorf("task definition is invalid: %s", aws.StringValue(client.Input.TaskDefinition))
+	}
+}
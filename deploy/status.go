@@ -0,0 +1,15 @@
+package deploy
+
+// Status is the status of the deploy which is passed to hooks and notifiers.
+type Status string
+
+const (
+	// StatusStarted is the status before the deploy.
+	StatusStarted Status = "started"
+	// StatusSucceeded is the status after the deploy succeeds.
+	StatusSucceeded Status = "succeeded"
+	// StatusFailed is the status after the deploy fails.
+	StatusFailed Status = "failed"
+	// StatusRolledBack is the status after the deploy fails and the service is rolled back.
+	StatusRolledBack Status = "rolled_back"
+)
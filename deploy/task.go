@@ -2,11 +2,12 @@ package deploy
 
 import (
 	"context"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
 	shellwords "github.com/mattn/go-shellwords"
@@ -33,48 +34,95 @@ type Task struct {
 	// Task command which run on ECS.
 	Command []*string
 
+	// Environment variables which override the container.
+	Environment []*ecs.KeyValuePair
+
+	// Number of cpu units reserved for the container.
+	ContainerCPU *int64
+
+	// Hard limit (in MiB) of memory for the container.
+	ContainerMemory *int64
+
+	// Number of tasks to run at once, from 1 to 10.
+	// If this is 0, one task is run.
+	Count int64
+
+	// Name of the environment variable which passes the shard index to the container in batch mode.
+	// If this is empty, SHARD_INDEX is used.
+	ShardEnvironment string
+
+	// Overrides for the other containers in the task definition.
+	ContainerOverrides []*ecs.ContainerOverride
+
+	// Task-level cpu units which override the task definition.
+	CPU *string
+
+	// Task-level memory (in MiB) which overrides the task definition.
+	Memory *string
+
+	// IAM role which the containers in the task can assume.
+	TaskRoleArn *string
+
+	// IAM role which the container agent uses to pull images and publish logs.
+	ExecutionRoleArn *string
+
 	// Wait time when run task.
 	// This script monitors ECS task for new task definition to be running after call run task API.
 	Timeout time.Duration
 	// EC2 or Fargate
 	LaunchType string
+	// Capacity provider strategy to run the task.
+	// If you set this, LaunchType is ignored.
+	CapacityProviderStrategy []*ecs.CapacityProviderStrategyItem
+	// Platform version of Fargate, for example LATEST or 1.4.0.
+	PlatformVersion *string
 	// If you set Fargate as launch type, you have to set your subnet IDs.
 	// Because Fargate demands awsvpc as network configuration, so subnet IDs are required.
 	Subnets []*string
 	// If you want to attach the security groups to ENI of the task, please set this.
 	SecurityGroups []*string
-	// If you don't enable this flag, the task access the internet throguth NAT gateway.
+	// ENABLED or DISABLED. If you don't enable this flag, the task access the internet throguth NAT gateway.
+	// If this is empty, DISABLED is used. It is available only for awsvpc network mode, and ENABLED is supported only on Fargate.
 	// Please read more information: https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-networking.html
 	AssignPublicIP string
-	verbose        bool
+	// Placement constraints which are applied to the task on EC2.
+	PlacementConstraints []*ecs.PlacementConstraint
+	// Placement strategy which is applied to the task on EC2.
+	PlacementStrategy []*ecs.PlacementStrategy
+	// Tags which are attached to the task.
+	Tags []*ecs.Tag
+	// TASK_DEFINITION or SERVICE to propagate the tags to the task.
+	PropagateTags *string
+	// Value of startedBy of the task. NewTask sets ecs-goploy to this.
+	StartedBy string
+	// Task group name of the task.
+	Group *string
+	// Service (cluster/service or service in the cluster) whose network configuration is inherited by the task.
+	// Subnets, security groups, public IP, launch type and capacity provider strategy are copied from the service,
+	// unless they are set explicitly to the task.
+	NetworkFromService string
+	// Logger of the task.
+	Logger *log.Entry
+	// Observers receive events while running the task. They may be called from multiple goroutines when the task runs as shards.
+	Observers []Observer
+	// Notifiers are notified when Run starts and finishes.
+	Notifiers []Notifier
+	// defaultLogger is used when Logger is nil.
+	defaultLogger *log.Entry
+	verbose       bool
 }
 
 // NewTask returns a new Task struct, and initialize aws ecs API client.
 // If you want to run the task as Fargate, please provide fargate flag to true, and your subnet IDs for awsvpc.
 // If you don't want to run the task as Fargate, please provide empty string for subnetIDs.
+// baseTaskDefinition can be empty if you only wait tasks, but it is required to run the task.
 func NewTask(cluster, name, command, baseTaskDefinition string, fargate bool, subnetIDs, securityGroupIDs string, timeout time.Duration, profile, region string, verbose bool) (*Task, error) {
-	if baseTaskDefinition == "" {
-		return nil, errors.New("task definition is required")
-	}
-	awsECS := ecs.New(session.New(), newConfig(profile, region))
-	taskDefinition := NewTaskDefinition(profile, region, verbose)
-	if !verbose {
-		log.SetLevel(log.ErrorLevel)
-	}
-	p := shellwords.NewParser()
-	commands, err := p.Parse(command)
+	t, err := NewTaskWithOptions(cluster, baseTaskDefinition, WithCommand(name, command), WithTimeout(timeout), WithProfile(profile), WithRegion(region), WithVerbose(verbose))
 	if err != nil {
-		return nil, errors.Wrap(err, "Parse error in a task command")
-	}
-	var cmd []*string
-	for _, c := range commands {
-		cmd = append(cmd, aws.String(c))
+		return nil, err
 	}
-	launchType := "EC2"
-	assignPublicIP := "DISABLED"
 	if fargate {
-		launchType = "FARGATE"
-		assignPublicIP = "ENABLED"
+		t.LaunchType = "FARGATE"
 	}
 	subnets := []*string{}
 	for _, s := range strings.Split(subnetIDs, ",") {
@@ -88,128 +136,515 @@ func NewTask(cluster, name, command, baseTaskDefinition string, fargate bool, su
 			securityGroups = append(securityGroups, aws.String(g))
 		}
 	}
+	t.Subnets = subnets
+	t.SecurityGroups = securityGroups
+	return t, nil
+}
 
+// NewTaskWithOptions returns a new Task struct configured with the options.
+// The task runs on EC2 by default. Please set LaunchType, Subnets and other fields to change it.
+func NewTaskWithOptions(cluster, baseTaskDefinition string, opts ...Option) (*Task, error) {
+	o := newOptions(opts)
+	cmd, err := parseCommand(o.command)
+	if err != nil {
+		return nil, err
+	}
+	awsECS := o.ecs()
+	logger := o.newLogger().WithField("cluster", cluster)
 	return &Task{
 		awsECS:             awsECS,
 		Cluster:            cluster,
-		Name:               name,
+		Name:               o.container,
 		BaseTaskDefinition: baseTaskDefinition,
-		TaskDefinition:     taskDefinition,
+		TaskDefinition:     &TaskDefinition{awsECS: awsECS, Logger: logger, verbose: o.verbose},
 		Command:            cmd,
-		Timeout:            timeout,
-		LaunchType:         launchType,
-		Subnets:            subnets,
-		SecurityGroups:     securityGroups,
-		AssignPublicIP:     assignPublicIP,
-		verbose:            verbose,
+		StartedBy:          "ecs-goploy",
+		Timeout:            o.timeout,
+		LaunchType:         "EC2",
+		Subnets:            []*string{},
+		SecurityGroups:     []*string{},
+		Logger:             logger,
+		Observers:          o.observers,
+		Notifiers:          o.notifiers,
+		verbose:            o.verbose,
 	}, nil
 }
 
-// RunTask calls run-task API.
-func (t *Task) RunTask(taskDefinition *ecs.TaskDefinition) ([]*ecs.Task, error) {
-	ctx, cancel := context.WithCancel(context.Background())
-	if t.Timeout != 0 {
-		ctx, cancel = context.WithTimeout(context.Background(), t.Timeout)
+func (t *Task) logger() *log.Entry {
+	if t.Logger != nil {
+		return t.Logger
 	}
-	defer cancel()
+	if t.defaultLogger == nil {
+		t.defaultLogger = newLogger(t.verbose)
+	}
+	return t.defaultLogger
+}
 
-	containerOverride := &ecs.ContainerOverride{
-		Command: t.Command,
-		Name:    aws.String(t.Name),
+func (t *Task) emit(event *Event) {
+	event.Cluster = t.Cluster
+	notifyObservers(t.Observers, event)
+}
+
+func (t *Task) sendNotification(n *Notification) {
+	n.Kind = "task"
+	n.Cluster = t.Cluster
+	if n.TaskDefinitionArn == "" {
+		n.TaskDefinitionArn = t.BaseTaskDefinition
+	}
+	notifyAll(t.Notifiers, n, t.logger())
+}
+
+// NewContainerOverride returns an override for the container in the task definition.
+// The command is parsed like a shell, and each environment variable is given as KEY=VALUE.
+func NewContainerOverride(name, command string, environment []string) (*ecs.ContainerOverride, error) {
+	if name == "" {
+		return nil, errors.New("container name is required")
+	}
+	cmd, err := parseCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	env, err := ParseEnvironment(environment)
+	if err != nil {
+		return nil, err
 	}
+	return &ecs.ContainerOverride{
+		Name:        aws.String(name),
+		Command:     cmd,
+		Environment: env,
+	}, nil
+}
 
-	override := &ecs.TaskOverride{
-		ContainerOverrides: []*ecs.ContainerOverride{
-			containerOverride,
-		},
+// ParseEnvironment converts KEY=VALUE strings to environment variables for the container override.
+func ParseEnvironment(environment []string) ([]*ecs.KeyValuePair, error) {
+	var env []*ecs.KeyValuePair
+	for _, e := range environment {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			return nil, errors.Errorf("environment variable format is wrong: %s", e)
+		}
+		env = append(env, &ecs.KeyValuePair{
+			Name:  aws.String(kv[0]),
+			Value: aws.String(kv[1]),
+		})
 	}
+	return env, nil
+}
 
-	var params *ecs.RunTaskInput
-	if len(t.Subnets) > 0 {
-		vpcConfiguration := &ecs.AwsVpcConfiguration{
-			AssignPublicIp: aws.String(t.AssignPublicIP),
-			Subnets:        t.Subnets,
-			SecurityGroups: t.SecurityGroups,
+func parseCommand(command string) ([]*string, error) {
+	p := shellwords.NewParser()
+	commands, err := p.Parse(command)
+	if err != nil {
+		return nil, errors.Wrap(err, "Parse error in a task command")
+	}
+	var cmd []*string
+	for _, c := range commands {
+		cmd = append(cmd, aws.String(c))
+	}
+	return cmd, nil
+}
+
+// taskOverride builds overrides of the task definition from the task settings.
+func (t *Task) taskOverride() *ecs.TaskOverride {
+	containerOverrides := []*ecs.ContainerOverride{}
+	if t.Name != "" {
+		containerOverrides = append(containerOverrides, &ecs.ContainerOverride{
+			Name:        aws.String(t.Name),
+			Command:     t.Command,
+			Environment: t.Environment,
+			Cpu:         t.ContainerCPU,
+			Memory:      t.ContainerMemory,
+		})
+	}
+	containerOverrides = append(containerOverrides, t.ContainerOverrides...)
+
+	return &ecs.TaskOverride{
+		ContainerOverrides: containerOverrides,
+		Cpu:                t.CPU,
+		Memory:             t.Memory,
+		TaskRoleArn:        t.TaskRoleArn,
+		ExecutionRoleArn:   t.ExecutionRoleArn,
+	}
+}
+
+// InheritNetworkConfiguration copies network configuration, launch type and capacity provider strategy from the service.
+// Subnets, security groups, public IP and capacity provider strategy which are already set to the task are kept.
+func (t *Task) InheritNetworkConfiguration(cluster, name string) error {
+	return t.InheritNetworkConfigurationWithContext(context.Background(), cluster, name)
+}
+
+// InheritNetworkConfigurationWithContext is the same as InheritNetworkConfiguration with the context.
+func (t *Task) InheritNetworkConfigurationWithContext(ctx context.Context, cluster, name string) error {
+	params := &ecs.DescribeServicesInput{
+		Cluster:  aws.String(cluster),
+		Services: []*string{aws.String(name)},
+	}
+	resp, err := t.awsECS.DescribeServicesWithContext(ctx, params)
+	if err != nil {
+		return err
+	}
+	if len(resp.Services) == 0 {
+		return errors.Errorf("service %s is not found in %s", name, cluster)
+	}
+	service := resp.Services[0]
+
+	if service.NetworkConfiguration != nil && service.NetworkConfiguration.AwsvpcConfiguration != nil {
+		vpc := service.NetworkConfiguration.AwsvpcConfiguration
+		if len(t.Subnets) == 0 {
+			t.Subnets = vpc.Subnets
+		}
+		if len(t.SecurityGroups) == 0 {
+			t.SecurityGroups = vpc.SecurityGroups
+		}
+		if t.AssignPublicIP == "" && vpc.AssignPublicIp != nil {
+			t.AssignPublicIP = *vpc.AssignPublicIp
+		}
+	}
+	if len(t.CapacityProviderStrategy) == 0 {
+		if len(service.CapacityProviderStrategy) > 0 {
+			t.CapacityProviderStrategy = service.CapacityProviderStrategy
+		} else if service.LaunchType != nil {
+			t.LaunchType = *service.LaunchType
+		}
+	}
+	if t.PlatformVersion == nil {
+		t.PlatformVersion = service.PlatformVersion
+	}
+	t.logger().Infof("Inherit network configuration from %s: %+v", name, service.NetworkConfiguration)
+	return nil
+}
+
+// networkFromService returns cluster and service name of NetworkFromService.
+func (t *Task) networkFromService() (string, string) {
+	res := strings.SplitN(t.NetworkFromService, "/", 2)
+	if len(res) == 2 {
+		return res[0], res[1]
+	}
+	return t.Cluster, t.NetworkFromService
+}
+
+// isFargate returns whether the task runs on Fargate.
+func (t *Task) isFargate() bool {
+	if len(t.CapacityProviderStrategy) > 0 {
+		for _, item := range t.CapacityProviderStrategy {
+			if item.CapacityProvider != nil && strings.HasPrefix(*item.CapacityProvider, "FARGATE") {
+				return true
+			}
 		}
-		network := &ecs.NetworkConfiguration{
-			AwsvpcConfiguration: vpcConfiguration,
+		return false
+	}
+	return t.LaunchType == "FARGATE"
+}
+
+// validateNetwork checks combinations of launch type, network mode of the task definition and network configuration
+// before calling run-task API.
+func (t *Task) validateNetwork(taskDefinition *ecs.TaskDefinition) error {
+	networkMode := "bridge"
+	if taskDefinition.NetworkMode != nil {
+		networkMode = *taskDefinition.NetworkMode
+	}
+	switch t.AssignPublicIP {
+	case "", "ENABLED", "DISABLED":
+	default:
+		return errors.Errorf("assign public IP must be ENABLED or DISABLED: %s", t.AssignPublicIP)
+	}
+
+	if t.isFargate() {
+		if networkMode != "awsvpc" {
+			return errors.Errorf("Fargate requires awsvpc network mode, but the network mode of %s is %s", *taskDefinition.TaskDefinitionArn, networkMode)
 		}
-		params = &ecs.RunTaskInput{
-			Cluster:              aws.String(t.Cluster),
-			TaskDefinition:       taskDefinition.TaskDefinitionArn,
-			Overrides:            override,
-			NetworkConfiguration: network,
-			LaunchType:           aws.String(t.LaunchType),
+		if len(t.Subnets) == 0 {
+			return errors.New("Fargate requires awsvpc network mode, so subnets are required")
 		}
+		return nil
+	}
+
+	if t.AssignPublicIP == "ENABLED" {
+		return errors.New("assign public IP is supported only on Fargate")
+	}
+	if len(t.Subnets) > 0 && networkMode != "awsvpc" {
+		return errors.Errorf("subnets and security groups are available only for awsvpc network mode, but the network mode of %s is %s", *taskDefinition.TaskDefinitionArn, networkMode)
+	}
+	if len(t.Subnets) == 0 && networkMode == "awsvpc" {
+		return errors.Errorf("network mode of %s is awsvpc, so subnets are required", *taskDefinition.TaskDefinitionArn)
+	}
+	if len(t.Subnets) == 0 && len(t.SecurityGroups) > 0 {
+		return errors.New("security groups require subnets")
+	}
+	return nil
+}
+
+// runTaskInput builds parameters of run-task API from the task settings.
+func (t *Task) runTaskInput(taskDefinition *ecs.TaskDefinition) *ecs.RunTaskInput {
+	params := &ecs.RunTaskInput{
+		Cluster:              aws.String(t.Cluster),
+		Count:                aws.Int64(1),
+		TaskDefinition:       taskDefinition.TaskDefinitionArn,
+		Overrides:            t.taskOverride(),
+		PlatformVersion:      t.PlatformVersion,
+		PlacementConstraints: t.PlacementConstraints,
+		PlacementStrategy:    t.PlacementStrategy,
+		Tags:                 t.Tags,
+		PropagateTags:        t.PropagateTags,
+		Group:                t.Group,
+	}
+	if t.StartedBy != "" {
+		params.StartedBy = aws.String(t.StartedBy)
+	}
+	if t.Count > 0 {
+		params.Count = aws.Int64(t.Count)
+	}
+	// Launch type can not be specified with capacity provider strategy.
+	if len(t.CapacityProviderStrategy) > 0 {
+		params.CapacityProviderStrategy = t.CapacityProviderStrategy
 	} else {
-		params = &ecs.RunTaskInput{
-			Cluster:        aws.String(t.Cluster),
-			TaskDefinition: taskDefinition.TaskDefinitionArn,
-			Overrides:      override,
-			LaunchType:     aws.String(t.LaunchType),
+		params.LaunchType = aws.String(t.LaunchType)
+	}
+	if len(t.Subnets) > 0 {
+		assignPublicIP := t.AssignPublicIP
+		if assignPublicIP == "" {
+			assignPublicIP = "DISABLED"
+		}
+		params.NetworkConfiguration = &ecs.NetworkConfiguration{
+			AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
+				AssignPublicIp: aws.String(assignPublicIP),
+				Subnets:        t.Subnets,
+				SecurityGroups: t.SecurityGroups,
+			},
+		}
+	}
+	return params
+}
+
+// RunTask calls run-task API.
+// It waits until all tasks stop, and returns the stopped tasks.
+func (t *Task) RunTask(taskDefinition *ecs.TaskDefinition) ([]*ecs.Task, error) {
+	return t.RunTaskWithContext(context.Background(), taskDefinition)
+}
+
+// RunTaskWithContext is the same as RunTask with the context.
+// Timeout of the task is applied in addition to the deadline of the context.
+func (t *Task) RunTaskWithContext(ctx context.Context, taskDefinition *ecs.TaskDefinition) ([]*ecs.Task, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	if t.Timeout != 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+	}
+	defer cancel()
+
+	tasks, err := t.startTask(ctx, taskDefinition)
+	if err != nil {
+		return nil, err
+	}
+
+	stopped, err := t.waitRunning(ctx, tasks)
+	if err != nil {
+		if stopped != nil {
+			return stopped, err
 		}
+		return tasks, err
+	}
+	return stopped, nil
+}
+
+// StartTask calls run-task API, and returns the started tasks without waiting.
+// Please use Wait to wait the tasks.
+func (t *Task) StartTask(taskDefinition *ecs.TaskDefinition) ([]*ecs.Task, error) {
+	return t.StartTaskWithContext(context.Background(), taskDefinition)
+}
+
+// StartTaskWithContext is the same as StartTask with the context.
+func (t *Task) StartTaskWithContext(ctx context.Context, taskDefinition *ecs.TaskDefinition) ([]*ecs.Task, error) {
+	return t.startTask(ctx, taskDefinition)
+}
+
+// Wait waits until all tasks stop, and returns the stopped tasks.
+// If some tasks exit with non-zero code, it returns the stopped tasks with an error as same as RunTask.
+func (t *Task) Wait(ctx context.Context, taskArns []string) ([]*ecs.Task, error) {
+	if len(taskArns) == 0 {
+		return nil, errors.New("task ARN is required")
+	}
+	tasks := []*ecs.Task{}
+	for _, arn := range taskArns {
+		tasks = append(tasks, &ecs.Task{TaskArn: aws.String(arn)})
 	}
+	return t.waitRunning(ctx, tasks)
+}
 
+func (t *Task) startTask(ctx context.Context, taskDefinition *ecs.TaskDefinition) ([]*ecs.Task, error) {
+	if err := t.validateNetwork(taskDefinition); err != nil {
+		return nil, err
+	}
+	params := t.runTaskInput(taskDefinition)
 	resp, err := t.awsECS.RunTaskWithContext(ctx, params)
 	if err != nil {
 		return nil, err
 	}
 	if len(resp.Failures) > 0 {
-		log.Errorf("Run task error: %+v", resp.Failures)
+		t.logger().Errorf("Run task error: %+v", resp.Failures)
 		return nil, errors.New(*resp.Failures[0].Reason)
 	}
-	log.Infof("Running tasks: %+v", resp.Tasks)
-
-	err = t.waitRunning(ctx, resp.Tasks)
-	if err != nil {
-		return resp.Tasks, err
+	for _, task := range resp.Tasks {
+		t.logger().Infof("Running task: %s", aws.StringValue(task.TaskArn))
 	}
+	t.emit(&Event{
+		Type:              EventTasksStarted,
+		TaskDefinitionArn: aws.StringValue(taskDefinition.TaskDefinitionArn),
+		TaskArns:          eventTaskArns(resp.Tasks),
+	})
 	return resp.Tasks, nil
 }
 
+// RunBatch runs the task for each shard, and keeps at most concurrency tasks running.
+// The shard index (0 to shards - 1) is passed to the container as ShardEnvironment.
+// Even if some shards fail, it waits all shards and returns results of all tasks.
+func (t *Task) RunBatch(taskDefinition *ecs.TaskDefinition, shards, concurrency int) ([]*TaskResult, error) {
+	return t.RunBatchWithContext(context.Background(), taskDefinition, shards, concurrency)
+}
+
+// RunBatchWithContext is the same as RunBatch with the context.
+// If the context is canceled, shards which have not started yet are not run.
+func (t *Task) RunBatchWithContext(ctx context.Context, taskDefinition *ecs.TaskDefinition, shards, concurrency int) ([]*TaskResult, error) {
+	if t.Name == "" {
+		return nil, errors.New("container name is required to pass the shard index")
+	}
+	if shards <= 0 {
+		return nil, errors.New("shards must be greater than 0")
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if err := t.validateNetwork(taskDefinition); err != nil {
+		return nil, err
+	}
+	shardEnvironment := t.ShardEnvironment
+	if shardEnvironment == "" {
+		shardEnvironment = "SHARD_INDEX"
+	}
+
+	// Shards share the logger, so the default logger is not created in each goroutine.
+	logger := t.logger()
+	results := make([][]*TaskResult, shards)
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < shards; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(index int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if ctx.Err() != nil {
+				results[index] = []*TaskResult{
+					&TaskResult{
+						Shard: index,
+						Err:   contextError(ctx),
+					},
+				}
+				return
+			}
+			shard := *t
+			shard.Count = 1
+			shard.Logger = logger.WithField("shard", index)
+			shard.Environment = append(append([]*ecs.KeyValuePair{}, t.Environment...), &ecs.KeyValuePair{
+				Name:  aws.String(shardEnvironment),
+				Value: aws.String(strconv.Itoa(index)),
+			})
+			tasks, err := shard.RunTaskWithContext(ctx, taskDefinition)
+			if len(tasks) == 0 {
+				results[index] = []*TaskResult{
+					&TaskResult{
+						Shard: index,
+						Err:   err,
+					},
+				}
+				return
+			}
+			rs := NewTaskResults(tasks)
+			for _, r := range rs {
+				r.Shard = index
+				r.Err = err
+			}
+			results[index] = rs
+		}(i)
+	}
+	wg.Wait()
+
+	all := []*TaskResult{}
+	failed := 0
+	for _, rs := range results {
+		for _, r := range rs {
+			if !r.Succeeded() {
+				failed++
+			}
+			all = append(all, r)
+		}
+	}
+	if failed > 0 {
+		return all, errors.Errorf("%d of %d tasks failed", failed, len(all))
+	}
+	return all, nil
+}
+
 // waitRunning waits a task running.
-func (t *Task) waitRunning(ctx context.Context, tasks []*ecs.Task) error {
-	log.Info("Waiting for running task...")
+func (t *Task) waitRunning(ctx context.Context, tasks []*ecs.Task) ([]*ecs.Task, error) {
+	t.logger().Info("Waiting for running task...")
 
 	taskArns := []*string{}
 	for _, task := range tasks {
 		taskArns = append(taskArns, task.TaskArn)
 	}
-	errCh := make(chan error, 1)
-	done := make(chan struct{}, 1)
+	type result struct {
+		tasks []*ecs.Task
+		err   error
+	}
+	resultCh := make(chan result, 1)
 	go func() {
-		err := t.waitExitTasks(taskArns)
-		if err != nil {
-			errCh <- err
-		}
-		close(done)
+		stopped, err := t.waitExitTasks(ctx, taskArns)
+		resultCh <- result{stopped, err}
 	}()
 	select {
-	case err := <-errCh:
-		if err != nil {
-			return err
+	case r := <-resultCh:
+		if r.tasks != nil {
+			t.emit(&Event{
+				Type:     EventTasksStopped,
+				TaskArns: eventTaskArns(r.tasks),
+				Err:      r.err,
+			})
+		}
+		if r.err != nil {
+			return r.tasks, r.err
 		}
-	case <-done:
-		log.Info("Run task is success")
+		t.logger().Info("Run task is success")
+		return r.tasks, nil
 	case <-ctx.Done():
-		return errors.New("process timeout")
+		return nil, contextError(ctx)
 	}
-
-	return nil
 }
 
-func (t *Task) waitExitTasks(taskArns []*string) error {
+// waitExitTasks waits until all tasks stop, and returns the stopped tasks.
+// If some tasks exit with non-zero code, it returns the stopped tasks with an error.
+// It returns when the context is done, so the polling goroutine does not leak.
+func (t *Task) waitExitTasks(ctx context.Context, taskArns []*string) ([]*ecs.Task, error) {
 retry:
 	for {
-		time.Sleep(5 * time.Second)
+		if err := sleep(ctx, 5*time.Second); err != nil {
+			return nil, err
+		}
 
 		params := &ecs.DescribeTasksInput{
 			Cluster: aws.String(t.Cluster),
 			Tasks:   taskArns,
 		}
-		resp, err := t.awsECS.DescribeTasks(params)
+		resp, err := t.awsECS.DescribeTasksWithContext(ctx, params)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if len(resp.Failures) > 0 {
+			f := resp.Failures[0]
+			return nil, errors.Errorf("failed to describe task %s: %s", aws.StringValue(f.Arn), aws.StringValue(f.Reason))
+		}
+		if len(resp.Tasks) != len(taskArns) {
+			return nil, errors.Errorf("%d tasks are described, but %d tasks are expected", len(resp.Tasks), len(taskArns))
 		}
 
 		for _, task := range resp.Tasks {
@@ -218,16 +653,23 @@ retry:
 			}
 		}
 
+		var failure error
 		for _, task := range resp.Tasks {
 			code, result, err := t.checkTaskSucceeded(task)
 			if err != nil {
-				continue retry
+				if !t.checkTaskFailedToStart(task) {
+					continue retry
+				}
+				if failure == nil {
+					failure = errors.Errorf("task failed to start: %s", aws.StringValue(task.StoppedReason))
+				}
+				continue
 			}
-			if !result {
-				return errors.Errorf("exit code: %v", code)
+			if !result && failure == nil {
+				failure = errors.Errorf("exit code: %v", code)
 			}
 		}
-		return nil
+		return resp.Tasks, failure
 	}
 }
 
@@ -238,6 +680,11 @@ func (t *Task) checkTaskStopped(task *ecs.Task) bool {
 	return true
 }
 
+// checkTaskFailedToStart returns whether the task stopped before containers start, so exit codes are never reported.
+func (t *Task) checkTaskFailedToStart(task *ecs.Task) bool {
+	return aws.StringValue(task.StopCode) == ecs.TaskStopCodeTaskFailedToStart
+}
+
 func (t *Task) checkTaskSucceeded(task *ecs.Task) (int64, bool, error) {
 	for _, c := range task.Containers {
 		if c.ExitCode == nil {
@@ -249,3 +696,69 @@ func (t *Task) checkTaskSucceeded(task *ecs.Task) (int64, bool, error) {
 	}
 	return int64(0), true, nil
 }
+
+// TaskResult has a result of the stopped task.
+type TaskResult struct {
+	// Index of the shard in batch mode.
+	Shard int
+
+	// ARN of the task. If the task could not be run, this is empty.
+	TaskArn string
+
+	// ARN of the task definition which the task ran.
+	TaskDefinitionArn string
+
+	// Exit code of the task, which is the first non-zero exit code in the containers.
+	// If the exit code can not be read, for example the task failed to start, this is nil.
+	ExitCode *int64
+
+	// Duration from the task started (or created if it was not started) until it stopped.
+	Duration time.Duration
+
+	// Reason why the task stopped.
+	StoppedReason string
+
+	// Error which occurred while running the task.
+	Err error
+}
+
+// NewTaskResults returns results of the stopped tasks.
+func NewTaskResults(tasks []*ecs.Task) []*TaskResult {
+	results := []*TaskResult{}
+	for _, task := range tasks {
+		r := &TaskResult{
+			TaskArn:           aws.StringValue(task.TaskArn),
+			TaskDefinitionArn: aws.StringValue(task.TaskDefinitionArn),
+			StoppedReason:     aws.StringValue(task.StoppedReason),
+		}
+		// Keep the first non-zero exit code. If no container fails, the exit code is unknown until all containers exit.
+		exited := len(task.Containers) > 0
+		for _, c := range task.Containers {
+			if c.ExitCode == nil {
+				exited = false
+				continue
+			}
+			if *c.ExitCode != 0 {
+				r.ExitCode = aws.Int64(*c.ExitCode)
+				break
+			}
+		}
+		if r.ExitCode == nil && exited {
+			r.ExitCode = aws.Int64(0)
+		}
+		startedAt := task.StartedAt
+		if startedAt == nil {
+			startedAt = task.CreatedAt
+		}
+		if startedAt != nil && task.StoppedAt != nil {
+			r.Duration = task.StoppedAt.Sub(*startedAt)
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// Succeeded returns whether all containers of the task exited with zero.
+func (r *TaskResult) Succeeded() bool {
+	return r.Err == nil && r.ExitCode != nil && *r.ExitCode == 0
+}
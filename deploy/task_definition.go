@@ -1,27 +1,50 @@
 package deploy
 
 import (
+	"context"
+
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 // TaskDefinition has image and task definition information.
 type TaskDefinition struct {
 	awsECS ecsiface.ECSAPI
 
-	verbose bool
+	// Logger of the task definition.
+	Logger *log.Entry
+
+	// defaultLogger is used when Logger is nil.
+	defaultLogger *log.Entry
+	verbose       bool
 }
 
 // NewTaskDefinition initializes aws ecs API client, and returns a task definition struct.
 func NewTaskDefinition(profile, region string, verbose bool) *TaskDefinition {
-	awsECS := ecs.New(session.New(), newConfig(profile, region))
+	return NewTaskDefinitionWithOptions(WithProfile(profile), WithRegion(region), WithVerbose(verbose))
+}
+
+// NewTaskDefinitionWithOptions returns a task definition struct configured with the options.
+func NewTaskDefinitionWithOptions(opts ...Option) *TaskDefinition {
+	o := newOptions(opts)
 	return &TaskDefinition{
-		awsECS,
-		verbose,
+		awsECS:  o.ecs(),
+		Logger:  o.newLogger(),
+		verbose: o.verbose,
+	}
+}
+
+func (d *TaskDefinition) logger() *log.Entry {
+	if d.Logger != nil {
+		return d.Logger
+	}
+	if d.defaultLogger == nil {
+		d.defaultLogger = newLogger(d.verbose)
 	}
+	return d.defaultLogger
 }
 
 // DescribeTaskDefinition gets a task definition.
@@ -29,10 +52,15 @@ func NewTaskDefinition(profile, region string, verbose bool) *TaskDefinition {
 // for a specific revision in the family, or full Amazon Resource Name (ARN)
 // of the task definition to describe.
 func (d *TaskDefinition) DescribeTaskDefinition(taskDefinitionName string) (*ecs.TaskDefinition, error) {
+	return d.DescribeTaskDefinitionWithContext(context.Background(), taskDefinitionName)
+}
+
+// DescribeTaskDefinitionWithContext is the same as DescribeTaskDefinition with the context.
+func (d *TaskDefinition) DescribeTaskDefinitionWithContext(ctx context.Context, taskDefinitionName string) (*ecs.TaskDefinition, error) {
 	params := &ecs.DescribeTaskDefinitionInput{
 		TaskDefinition: aws.String(taskDefinitionName),
 	}
-	resp, err := d.awsECS.DescribeTaskDefinition(params)
+	resp, err := d.awsECS.DescribeTaskDefinitionWithContext(ctx, params)
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +71,11 @@ func (d *TaskDefinition) DescribeTaskDefinition(taskDefinitionName string) (*ecs
 // RegisterTaskDefinition registers new task definition if needed.
 // If newTask is not set, returns a task definition which same as the given task definition.
 func (d *TaskDefinition) RegisterTaskDefinition(baseDefinition *ecs.TaskDefinition, newImage *Image) (*ecs.TaskDefinition, error) {
+	return d.RegisterTaskDefinitionWithContext(context.Background(), baseDefinition, newImage)
+}
+
+// RegisterTaskDefinitionWithContext is the same as RegisterTaskDefinition with the context.
+func (d *TaskDefinition) RegisterTaskDefinitionWithContext(ctx context.Context, baseDefinition *ecs.TaskDefinition, newImage *Image) (*ecs.TaskDefinition, error) {
 	var containerDefinitions []*ecs.ContainerDefinition
 	for _, c := range baseDefinition.ContainerDefinitions {
 		newDefinition, err := d.NewContainerDefinition(c, newImage)
@@ -66,7 +99,7 @@ func (d *TaskDefinition) RegisterTaskDefinition(baseDefinition *ecs.TaskDefiniti
 		Volumes:                 baseDefinition.Volumes,
 	}
 
-	resp, err := d.awsECS.RegisterTaskDefinition(params)
+	resp, err := d.awsECS.RegisterTaskDefinitionWithContext(ctx, params)
 	if err != nil {
 		return nil, err
 	}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
 )
@@ -13,7 +14,7 @@ type mockedDescribeTaskDefinition struct {
 	Resp ecs.DescribeTaskDefinitionOutput
 }
 
-func (m mockedDescribeTaskDefinition) DescribeTaskDefinition(in *ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error) {
+func (m mockedDescribeTaskDefinition) DescribeTaskDefinitionWithContext(ctx aws.Context, in *ecs.DescribeTaskDefinitionInput, opts ...request.Option) (*ecs.DescribeTaskDefinitionOutput, error) {
 	return &m.Resp, nil
 }
 
@@ -42,7 +43,7 @@ type mockedRegisterTaskDefinition struct {
 	Resp ecs.RegisterTaskDefinitionOutput
 }
 
-func (m mockedRegisterTaskDefinition) RegisterTaskDefinition(in *ecs.RegisterTaskDefinitionInput) (*ecs.RegisterTaskDefinitionOutput, error) {
+func (m mockedRegisterTaskDefinition) RegisterTaskDefinitionWithContext(ctx aws.Context, in *ecs.RegisterTaskDefinitionInput, opts ...request.Option) (*ecs.RegisterTaskDefinitionOutput, error) {
 	return &m.Resp, nil
 }
 
@@ -73,6 +74,51 @@ func TestRegisterTaskDefinition(t *testing.T) {
 	}
 }
 
+type mockedCreateTaskDefinition struct {
+	ecsiface.ECSAPI
+	Describe ecs.DescribeTaskDefinitionOutput
+	Register ecs.RegisterTaskDefinitionOutput
+}
+
+func (m mockedCreateTaskDefinition) DescribeTaskDefinitionWithContext(ctx aws.Context, in *ecs.DescribeTaskDefinitionInput, opts ...request.Option) (*ecs.DescribeTaskDefinitionOutput, error) {
+	return &m.Describe, nil
+}
+
+func (m mockedCreateTaskDefinition) RegisterTaskDefinitionWithContext(ctx aws.Context, in *ecs.RegisterTaskDefinitionInput, opts ...request.Option) (*ecs.RegisterTaskDefinitionOutput, error) {
+	return &m.Register, nil
+}
+
+func TestCreateWithResult(t *testing.T) {
+	describe := ecs.DescribeTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{
+			Family:            aws.String("dummy"),
+			TaskDefinitionArn: aws.String("dummy:1"),
+			ContainerDefinitions: []*ecs.ContainerDefinition{
+				&ecs.ContainerDefinition{
+					Name:  aws.String("web"),
+					Image: aws.String("nginx:stable"),
+				},
+			},
+		},
+	}
+	register := ecs.RegisterTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{
+			Family:            aws.String("dummy"),
+			TaskDefinitionArn: aws.String("dummy:2"),
+		},
+	}
+	taskDefinition := &TaskDefinition{
+		awsECS: mockedCreateTaskDefinition{Describe: describe, Register: register},
+	}
+	result, err := taskDefinition.CreateWithResult(aws.String("dummy:1"), "nginx:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TaskDefinitionArn != "dummy:2" || result.PreviousTaskDefinitionArn != "dummy:1" {
+		t.Errorf("result is invalid: %+v", result)
+	}
+}
+
 type mockedECS struct {
 	ecsiface.ECSAPI
 }
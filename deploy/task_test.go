@@ -1,6 +1,7 @@
 package deploy
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -20,7 +21,7 @@ func (m mockedRunTask) RunTaskWithContext(ctx aws.Context, in *ecs.RunTaskInput,
 	return &m.Run, nil
 }
 
-func (m mockedRunTask) DescribeTasks(in *ecs.DescribeTasksInput) (*ecs.DescribeTasksOutput, error) {
+func (m mockedRunTask) DescribeTasksWithContext(ctx aws.Context, in *ecs.DescribeTasksInput, opts ...request.Option) (*ecs.DescribeTasksOutput, error) {
 	return &m.Describe, nil
 }
 
@@ -69,3 +70,352 @@ func TestRunTask(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestNewContainerOverride(t *testing.T) {
+	override, err := NewContainerOverride("worker", "echo 'hello world'", []string{"FOO=bar", "BAZ=a=b"})
+	if err != nil {
+		t.Error(err)
+	}
+	if *override.Name != "worker" {
+		t.Errorf("name is invalid: %s", *override.Name)
+	}
+	if len(override.Command) != 2 || *override.Command[1] != "hello world" {
+		t.Errorf("command is invalid: %v", override.Command)
+	}
+	if len(override.Environment) != 2 || *override.Environment[1].Name != "BAZ" || *override.Environment[1].Value != "a=b" {
+		t.Errorf("environment is invalid: %v", override.Environment)
+	}
+
+	if _, err := NewContainerOverride("worker", "", []string{"FOO"}); err == nil {
+		t.Error("environment without value should be error")
+	}
+}
+
+func TestTaskOverride(t *testing.T) {
+	task := &Task{
+		Name:         "web",
+		Command:      []*string{aws.String("echo")},
+		ContainerCPU: aws.Int64(256),
+		ContainerOverrides: []*ecs.ContainerOverride{
+			&ecs.ContainerOverride{
+				Name: aws.String("worker"),
+			},
+		},
+		Memory:      aws.String("1024"),
+		TaskRoleArn: aws.String("task-role-arn"),
+	}
+	override := task.taskOverride()
+	if len(override.ContainerOverrides) != 2 {
+		t.Fatalf("container overrides are invalid: %v", override.ContainerOverrides)
+	}
+	if *override.ContainerOverrides[0].Name != "web" || *override.ContainerOverrides[0].Cpu != 256 {
+		t.Errorf("container override is invalid: %v", override.ContainerOverrides[0])
+	}
+	if *override.Memory != "1024" || *override.TaskRoleArn != "task-role-arn" {
+		t.Errorf("task override is invalid: %v", override)
+	}
+}
+
+func TestRunTaskInputWithCapacityProvider(t *testing.T) {
+	task := &Task{
+		Cluster:    "dummy-cluster",
+		LaunchType: "FARGATE",
+		CapacityProviderStrategy: []*ecs.CapacityProviderStrategyItem{
+			&ecs.CapacityProviderStrategyItem{
+				CapacityProvider: aws.String("FARGATE_SPOT"),
+			},
+		},
+		PlatformVersion: aws.String("1.4.0"),
+	}
+	params := task.runTaskInput(&ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("task-definition-arn"),
+	})
+	if params.LaunchType != nil {
+		t.Errorf("launch type should not be set with capacity provider strategy: %s", *params.LaunchType)
+	}
+	if len(params.CapacityProviderStrategy) != 1 || *params.PlatformVersion != "1.4.0" {
+		t.Errorf("params is invalid: %v", params)
+	}
+}
+
+func TestInheritNetworkConfiguration(t *testing.T) {
+	resp := ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{
+			&ecs.Service{
+				ServiceName: aws.String("dummy-service"),
+				NetworkConfiguration: &ecs.NetworkConfiguration{
+					AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
+						AssignPublicIp: aws.String("DISABLED"),
+						Subnets:        []*string{aws.String("subnet-12abcde")},
+						SecurityGroups: []*string{aws.String("sg-0123asdb")},
+					},
+				},
+				CapacityProviderStrategy: []*ecs.CapacityProviderStrategyItem{
+					&ecs.CapacityProviderStrategyItem{
+						CapacityProvider: aws.String("FARGATE_SPOT"),
+					},
+				},
+				PlatformVersion: aws.String("LATEST"),
+			},
+		},
+	}
+	task := &Task{
+		awsECS:             mockedDescribeServices{Resp: resp},
+		Cluster:            "dummy-cluster",
+		LaunchType:         "EC2",
+		Subnets:            []*string{},
+		NetworkFromService: "other-cluster/dummy-service",
+	}
+	cluster, service := task.networkFromService()
+	if cluster != "other-cluster" || service != "dummy-service" {
+		t.Errorf("service is invalid: %s/%s", cluster, service)
+	}
+	if err := task.InheritNetworkConfiguration(cluster, service); err != nil {
+		t.Error(err)
+	}
+	if len(task.Subnets) != 1 || *task.Subnets[0] != "subnet-12abcde" || len(task.SecurityGroups) != 1 {
+		t.Errorf("network configuration is invalid: %v, %v", task.Subnets, task.SecurityGroups)
+	}
+	if task.AssignPublicIP != "DISABLED" {
+		t.Errorf("public IP is invalid: %s", task.AssignPublicIP)
+	}
+	if len(task.CapacityProviderStrategy) != 1 || *task.PlatformVersion != "LATEST" {
+		t.Errorf("capacity provider is invalid: %v", task.CapacityProviderStrategy)
+	}
+}
+
+func TestValidateNetwork(t *testing.T) {
+	awsvpc := &ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("awsvpc-task-definition-arn"),
+		NetworkMode:       aws.String("awsvpc"),
+	}
+	bridge := &ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("bridge-task-definition-arn"),
+		NetworkMode:       aws.String("bridge"),
+	}
+	subnets := []*string{aws.String("subnet-12abcde")}
+	cases := []struct {
+		title          string
+		task           *Task
+		taskDefinition *ecs.TaskDefinition
+		valid          bool
+	}{
+		{"fargate in private subnets", &Task{LaunchType: "FARGATE", Subnets: subnets, AssignPublicIP: "DISABLED"}, awsvpc, true},
+		{"fargate in public subnets", &Task{LaunchType: "FARGATE", Subnets: subnets, AssignPublicIP: "ENABLED"}, awsvpc, true},
+		{"fargate without subnets", &Task{LaunchType: "FARGATE"}, awsvpc, false},
+		{"fargate with bridge", &Task{LaunchType: "FARGATE", Subnets: subnets}, bridge, false},
+		{"fargate spot without subnets", &Task{LaunchType: "EC2", CapacityProviderStrategy: []*ecs.CapacityProviderStrategyItem{&ecs.CapacityProviderStrategyItem{CapacityProvider: aws.String("FARGATE_SPOT")}}}, awsvpc, false},
+		{"ec2 with bridge", &Task{LaunchType: "EC2"}, bridge, true},
+		{"ec2 with awsvpc", &Task{LaunchType: "EC2", Subnets: subnets}, awsvpc, true},
+		{"ec2 with public IP", &Task{LaunchType: "EC2", Subnets: subnets, AssignPublicIP: "ENABLED"}, awsvpc, false},
+		{"ec2 with subnets and bridge", &Task{LaunchType: "EC2", Subnets: subnets}, bridge, false},
+		{"ec2 with awsvpc and without subnets", &Task{LaunchType: "EC2"}, awsvpc, false},
+		{"invalid public IP", &Task{LaunchType: "FARGATE", Subnets: subnets, AssignPublicIP: "TRUE"}, awsvpc, false},
+	}
+	for _, c := range cases {
+		err := c.task.validateNetwork(c.taskDefinition)
+		if c.valid && err != nil {
+			t.Errorf("%s should be valid: %v", c.title, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s should be invalid", c.title)
+		}
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	runTask := ecs.RunTaskOutput{
+		Tasks: []*ecs.Task{
+			&ecs.Task{
+				TaskArn: aws.String("task-arn"),
+			},
+		},
+	}
+	startedAt := time.Now()
+	describe := ecs.DescribeTasksOutput{
+		Tasks: []*ecs.Task{
+			&ecs.Task{
+				TaskArn:    aws.String("task-arn"),
+				LastStatus: aws.String("STOPPED"),
+				StartedAt:  aws.Time(startedAt),
+				StoppedAt:  aws.Time(startedAt.Add(time.Minute)),
+				Containers: []*ecs.Container{
+					&ecs.Container{
+						ExitCode: aws.Int64(0),
+					},
+				},
+			},
+		},
+	}
+	task := &Task{
+		awsECS:  mockedRunTask{Run: runTask, Describe: describe},
+		Name:    "dummy",
+		Timeout: 10 * time.Second,
+	}
+	results, err := task.RunBatch(&ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("task-definition-arn"),
+	}, 3, 2)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("results are invalid: %v", results)
+	}
+	for i, r := range results {
+		if r.Shard != i || !r.Succeeded() || r.Duration != time.Minute {
+			t.Errorf("result is invalid: %+v", r)
+		}
+	}
+}
+
+func TestNewTaskResults(t *testing.T) {
+	tasks := []*ecs.Task{
+		&ecs.Task{
+			TaskArn:       aws.String("failed-task-arn"),
+			StoppedReason: aws.String("Essential container in task exited"),
+			Containers: []*ecs.Container{
+				&ecs.Container{
+					ExitCode: aws.Int64(0),
+				},
+				&ecs.Container{
+					ExitCode: aws.Int64(2),
+				},
+			},
+		},
+		&ecs.Task{
+			TaskArn:       aws.String("not-started-task-arn"),
+			StopCode:      aws.String(ecs.TaskStopCodeTaskFailedToStart),
+			StoppedReason: aws.String("CannotPullContainerError"),
+			Containers: []*ecs.Container{
+				&ecs.Container{},
+			},
+		},
+		&ecs.Task{
+			TaskArn: aws.String("partially-failed-task-arn"),
+			Containers: []*ecs.Container{
+				&ecs.Container{
+					ExitCode: aws.Int64(3),
+				},
+				&ecs.Container{},
+			},
+		},
+	}
+	results := NewTaskResults(tasks)
+	if *results[0].ExitCode != 2 || results[0].Succeeded() {
+		t.Errorf("result is invalid: %+v", results[0])
+	}
+	if results[1].ExitCode != nil || results[1].Succeeded() || results[1].StoppedReason != "CannotPullContainerError" {
+		t.Errorf("result is invalid: %+v", results[1])
+	}
+	if results[2].ExitCode == nil || *results[2].ExitCode != 3 {
+		t.Errorf("result is invalid: %+v", results[2])
+	}
+}
+
+func TestRunTaskInputWithPlacementAndTags(t *testing.T) {
+	task := &Task{
+		Cluster:    "dummy-cluster",
+		LaunchType: "EC2",
+		PlacementConstraints: []*ecs.PlacementConstraint{
+			&ecs.PlacementConstraint{
+				Type: aws.String("distinctInstance"),
+			},
+		},
+		Tags: []*ecs.Tag{
+			&ecs.Tag{
+				Key:   aws.String("team"),
+				Value: aws.String("batch"),
+			},
+		},
+		PropagateTags: aws.String("TASK_DEFINITION"),
+		StartedBy:     "ecs-goploy",
+	}
+	params := task.runTaskInput(&ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("task-definition-arn"),
+	})
+	if len(params.PlacementConstraints) != 1 || len(params.Tags) != 1 {
+		t.Errorf("params is invalid: %v", params)
+	}
+	if *params.PropagateTags != "TASK_DEFINITION" || *params.StartedBy != "ecs-goploy" || params.Group != nil {
+		t.Errorf("params is invalid: %v", params)
+	}
+}
+
+func TestWait(t *testing.T) {
+	describe := ecs.DescribeTasksOutput{
+		Tasks: []*ecs.Task{
+			&ecs.Task{
+				TaskArn:    aws.String("task-arn"),
+				LastStatus: aws.String("STOPPED"),
+				Containers: []*ecs.Container{
+					&ecs.Container{
+						ExitCode: aws.Int64(3),
+					},
+				},
+			},
+		},
+	}
+	task := &Task{
+		awsECS: mockedRunTask{Describe: describe},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	tasks, err := task.Wait(ctx, []string{"task-arn"})
+	if err == nil || err.Error() != "exit code: 3" {
+		t.Errorf("error is invalid: %v", err)
+	}
+	if len(tasks) != 1 || *tasks[0].TaskArn != "task-arn" {
+		t.Errorf("tasks are invalid: %v", tasks)
+	}
+}
+
+func TestWaitMissingTask(t *testing.T) {
+	describe := ecs.DescribeTasksOutput{
+		Failures: []*ecs.Failure{
+			&ecs.Failure{
+				Arn:    aws.String("task-arn"),
+				Reason: aws.String("MISSING"),
+			},
+		},
+	}
+	task := &Task{
+		awsECS: mockedRunTask{Describe: describe},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := task.Wait(ctx, []string{"task-arn"})
+	if err == nil || err.Error() != "failed to describe task task-arn: MISSING" {
+		t.Errorf("error is invalid: %v", err)
+	}
+}
+
+func TestRunTaskWithContextCanceled(t *testing.T) {
+	runTask := ecs.RunTaskOutput{
+		Tasks: []*ecs.Task{
+			&ecs.Task{TaskArn: aws.String("task-arn")},
+		},
+	}
+	describe := ecs.DescribeTasksOutput{
+		Tasks: []*ecs.Task{
+			&ecs.Task{TaskArn: aws.String("task-arn"), LastStatus: aws.String("RUNNING")},
+		},
+	}
+	task := &Task{
+		awsECS: mockedRunTask{Run: runTask, Describe: describe},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	startedAt := time.Now()
+	_, err := task.RunTaskWithContext(ctx, &ecs.TaskDefinition{
+		TaskDefinitionArn: aws.String("task-definition-arn"),
+	})
+	if err != context.Canceled {
+		t.Errorf("error should be canceled: %v", err)
+	}
+	if time.Since(startedAt) > 3*time.Second {
+		t.Error("task should stop waiting when the context is canceled")
+	}
+}
@@ -3,7 +3,7 @@ module github.com/h3poteto/ecs-goploy
 go 1.13
 
 require (
-	github.com/aws/aws-sdk-go v1.25.25
+	github.com/aws/aws-sdk-go v1.55.8
 	github.com/mattn/go-shellwords v1.0.3
 	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.4.2